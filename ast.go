@@ -2,13 +2,13 @@ package mdx
 
 import (
 	"fmt"
-	"slices"
 	"strings"
 )
 
 const INDENT = "    "
 const MAX_LENGTH = 120
 
+// ComponentType classifies how a node is laid out when rendered.
 type ComponentType int
 
 const (
@@ -16,148 +16,111 @@ const (
 	Inline
 )
 
-type component interface {
+// Node is implemented by every element of a parsed MDX document.
+// Nodes defined in this package are rendered by HtmlRenderer; see Renderer for customising their output.
+type Node interface {
 	// Converts component into unformatted HTML
 	Raw() string
 	// Classifies component as either Block or Inline
 	Type() ComponentType
 	// Converts component into formatted HTML
 	Html(indentLevel int) string
+	// Returns where the component was found in the source
+	Range() SourceRange
 }
 
-type property struct {
+// SourceRange is the part of the MDX source a node was parsed from. End is exclusive.
+type SourceRange struct {
+	Start Position
+	End   Position
+}
+
+// Range returns the source range of the node. Nodes constructed outside of the parser have a zero range.
+func (r SourceRange) Range() SourceRange {
+	return r
+}
+
+func (r *SourceRange) setRange(sourceRange SourceRange) {
+	*r = sourceRange
+}
+
+// Property is a name/value pair declared with { .name=value } and rendered as an HTML attribute.
+type Property struct {
 	Name  string
 	Value string
 }
 
-type fragment struct {
+// Fragment is a run of plain text.
+type Fragment struct {
+	SourceRange
 	Value string
 }
 
-func (f *fragment) String() string {
+func (f *Fragment) String() string {
 	return fmt.Sprintf("Fragment{%s}", f.Value)
 }
 
-func (f *fragment) Raw() string {
-	return f.Value
+func (f *Fragment) Raw() string {
+	return rawString(f)
 }
 
-func (f *fragment) Type() ComponentType {
+func (f *Fragment) Type() ComponentType {
 	return Inline
 }
 
-func (f *fragment) Html(indentLevel int) string {
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-	formattedOutput := indentPrefix + f.Value
-	return formattedOutput
+func (f *Fragment) Html(indentLevel int) string {
+	return htmlString(f, indentLevel)
 }
 
-type lineBreak struct{}
+// LineBreak is rendered as <br/>.
+type LineBreak struct {
+	SourceRange
+}
 
-func (lb *lineBreak) Raw() string {
-	return "<br/>"
+func (lb *LineBreak) Raw() string {
+	return rawString(lb)
 }
 
-func (lb *lineBreak) Type() ComponentType {
+func (lb *LineBreak) Type() ComponentType {
 	return Block
 }
 
-func (lb *lineBreak) Html(indentLevel int) string {
-	tag := "<br/>"
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-	formattedOutput := "\n" + indentPrefix + tag + "\n"
-	return formattedOutput
+func (lb *LineBreak) Html(indentLevel int) string {
+	return htmlString(lb, indentLevel)
 }
 
-type header struct {
-	Properties []property
+// Heading is a # to ###### header, rendered as <h1> to <h6>.
+type Heading struct {
+	SourceRange
+	Properties []Property
 	Level      int
-	Content    []component
+	Content    []Node
 }
 
-func (h *header) InnerHtml() string {
-	var contentString string
-	for _, child := range h.Content {
-		contentString += child.Raw()
-	}
-	return contentString
+func (h *Heading) InnerHtml() string {
+	return rawString(h.Content...)
 }
 
-func (h *header) Raw() string {
-	var propertyString string
-	for _, property := range h.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	return fmt.Sprintf("<h%d%s>%s</h%d>", h.Level, propertyString, h.InnerHtml(), h.Level)
+func (h *Heading) Raw() string {
+	return rawString(h)
 }
 
-func (h *header) Type() ComponentType {
+func (h *Heading) Type() ComponentType {
 	return Block
 }
 
-func (h *header) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range h.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<h%d%s>", h.Level, propertyString)
-	closingTag := fmt.Sprintf("</h%d>", h.Level)
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-
-	formattedOutput := "\n" + indentPrefix + openingTag
-
-	containsBlockElement := slices.ContainsFunc(h.Content, func(c component) bool {
-		return c.Type() == Block
-	})
-
-	if containsBlockElement {
-		// put child component on new line and indented + 1
-		if len(h.Content) > 0 && h.Content[0].Type() == Inline {
-			formattedOutput += "\n"
-		}
-
-		var inlineString string
-		for _, child := range h.Content {
-			if child.Type() == Inline {
-				inlineString += indentPrefix + INDENT + child.Raw()
-			} else {
-				if len(inlineString) > 0 {
-					// split the inline string and append each
-					lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-					appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-					inlineString = ""
-				}
-
-				formattedOutput += child.Html(indentLevel + 1)
-			}
-		}
-		if len(inlineString) > 0 {
-			// split the inline string and append each
-			lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-			appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-			inlineString = ""
-		}
-		formattedOutput += "\n" + indentPrefix
-	} else {
-		// check if everything can fit on one line. if not, figure it out
-		inlineString := h.InnerHtml()
-		lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-		appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-	}
-
-	formattedOutput += closingTag + "\n"
-
-	return formattedOutput
+func (h *Heading) Html(indentLevel int) string {
+	return htmlString(h, indentLevel)
 }
 
-type paragraph struct {
-	Properties []property
-	Content    []component
+// Paragraph is a block of text, rendered as <p>.
+type Paragraph struct {
+	SourceRange
+	Properties []Property
+	Content    []Node
 }
 
-func (p *paragraph) String() string {
+func (p *Paragraph) String() string {
 	var contentString string
 	for _, child := range p.Content {
 		contentString += fmt.Sprintf("%s ", child)
@@ -165,638 +128,254 @@ func (p *paragraph) String() string {
 	return fmt.Sprintf("Paragraph{Content=[%s]}", strings.TrimSpace(contentString))
 }
 
-func (p *paragraph) InnerHtml() string {
-	var contentString string
-	for _, child := range p.Content {
-		contentString += child.Raw()
-	}
-	return contentString
+func (p *Paragraph) InnerHtml() string {
+	return rawString(p.Content...)
 }
 
-func (p *paragraph) Raw() string {
-	var propertyString string
-	for _, property := range p.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	return fmt.Sprintf("<p%s>%s</p>", propertyString, p.InnerHtml())
+func (p *Paragraph) Raw() string {
+	return rawString(p)
 }
 
-func (p *paragraph) Type() ComponentType {
+func (p *Paragraph) Type() ComponentType {
 	return Inline
 }
 
-func (p *paragraph) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range p.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<p%s>", propertyString)
-	closingTag := "</p>"
-
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-	formattedOutput := openingTag
-
-	containsBlockElement := slices.ContainsFunc(p.Content, func(c component) bool {
-		return c.Type() == Block
-	})
-
-	if containsBlockElement {
-		// put child component on new line and indented + 1
-		if len(p.Content) > 0 && p.Content[0].Type() == Inline {
-			formattedOutput += "\n"
-		}
-
-		var inlineString string
-		for _, child := range p.Content {
-			if child.Type() == Inline {
-				inlineString += indentPrefix + INDENT + child.Raw()
-			} else {
-				if len(inlineString) > 0 {
-					// split the inline string and append each
-					lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-					appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-					inlineString = ""
-				}
-
-				formattedOutput += child.Html(indentLevel + 1)
-			}
-		}
-		if len(inlineString) > 0 {
-			// split the inline string and append each
-			lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-			appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-			formattedOutput += "\n" + indentPrefix
-		} else {
-			formattedOutput += indentPrefix
-		}
-	} else {
-		// check if everything can fit on one line. if not, figure it out
-		inlineString := p.InnerHtml()
-		lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-		appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-	}
-
-	formattedOutput += closingTag + "\n"
-
-	return formattedOutput
+func (p *Paragraph) Html(indentLevel int) string {
+	return htmlString(p, indentLevel)
 }
 
-type code struct {
-	Properties []property
+// Code is inline code wrapped in backticks, rendered as <code>.
+type Code struct {
+	SourceRange
+	Properties []Property
 	Text       string
 }
 
-func (c *code) String() string {
+func (c *Code) String() string {
 	return fmt.Sprintf("Code{%s}", c.Text)
 }
 
-func (c *code) Raw() string {
-	var propertyString string
-	for _, property := range c.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-	return fmt.Sprintf("<code%s>%s</code>", propertyString, c.Text)
+func (c *Code) Raw() string {
+	return rawString(c)
 }
 
-func (c *code) Type() ComponentType {
+func (c *Code) Type() ComponentType {
 	return Inline
 }
 
-func (c *code) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range c.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	formattedOutput := fmt.Sprintf("<code%s>%s</code>\n", propertyString, c.Text)
-
-	return formattedOutput
+func (c *Code) Html(indentLevel int) string {
+	return htmlString(c, indentLevel)
 }
 
-type bold struct {
-	Properties []property
-	Content    []component
+// Bold is text wrapped in **, rendered as <strong>.
+type Bold struct {
+	SourceRange
+	Properties []Property
+	Content    []Node
 }
 
-func (b *bold) InnerHtml() string {
-	var contentString string
-	for _, child := range b.Content {
-		contentString += child.Raw()
-	}
-	return contentString
+func (b *Bold) InnerHtml() string {
+	return rawString(b.Content...)
 }
 
-func (b *bold) Raw() string {
-	var propertyString string
-	for _, property := range b.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-	return fmt.Sprintf("<strong%s>%s</strong>", propertyString, b.InnerHtml())
+func (b *Bold) Raw() string {
+	return rawString(b)
 }
 
-func (b *bold) Type() ComponentType {
+func (b *Bold) Type() ComponentType {
 	return Inline
 }
 
-func (b *bold) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range b.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<strong%s>", propertyString)
-	closingTag := "</strong>"
-
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-	formattedOutput := openingTag
-
-	containsBlockElement := slices.ContainsFunc(b.Content, func(c component) bool {
-		return c.Type() == Block
-	})
-
-	if containsBlockElement {
-		// put child component on new line and indented + 1
-		if len(b.Content) > 0 && b.Content[0].Type() == Inline {
-			formattedOutput += "\n"
-		}
-
-		var inlineString string
-		for _, child := range b.Content {
-			if child.Type() == Inline {
-				inlineString += indentPrefix + INDENT + child.Raw()
-			} else {
-				if len(inlineString) > 0 {
-					// split the inline string and append each
-					lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-					appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-					inlineString = ""
-				}
-
-				formattedOutput += child.Html(indentLevel + 1)
-			}
-		}
-		if len(inlineString) > 0 {
-			// split the inline string and append each
-			lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-			appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-			inlineString = ""
-		}
-	} else {
-		// check if everything can fit on one line. if not, figure it out
-		inlineString := b.InnerHtml()
-		lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-		appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-	}
-
-	formattedOutput += closingTag + "\n"
-
-	return formattedOutput
+func (b *Bold) Html(indentLevel int) string {
+	return htmlString(b, indentLevel)
 }
 
-type italic struct {
-	Properties []property
-	Content    []component
+// Italic is text wrapped in *, rendered as <em>.
+type Italic struct {
+	SourceRange
+	Properties []Property
+	Content    []Node
 }
 
-func (i *italic) InnerHtml() string {
-	var contentString string
-	for _, child := range i.Content {
-		contentString += child.Raw()
-	}
-	return contentString
+func (i *Italic) InnerHtml() string {
+	return rawString(i.Content...)
 }
 
-func (i *italic) Raw() string {
-	var propertyString string
-	for _, property := range i.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-	return fmt.Sprintf("<em%s>%s</em>", propertyString, i.InnerHtml())
+func (i *Italic) Raw() string {
+	return rawString(i)
 }
 
-func (i *italic) Type() ComponentType {
+func (i *Italic) Type() ComponentType {
 	return Inline
 }
 
-func (i *italic) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range i.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<em%s>", propertyString)
-	closingTag := "</em>"
-
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-	formattedOutput := openingTag
-
-	containsBlockElement := slices.ContainsFunc(i.Content, func(c component) bool {
-		return c.Type() == Block
-	})
-
-	if containsBlockElement {
-		// put child component on new line and indented + 1
-		if len(i.Content) > 0 && i.Content[0].Type() == Inline {
-			formattedOutput += "\n"
-		}
-
-		var inlineString string
-		for _, child := range i.Content {
-			if child.Type() == Inline {
-				inlineString += indentPrefix + INDENT + child.Raw()
-			} else {
-				if len(inlineString) > 0 {
-					// split the inline string and append each
-					lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-					appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-					inlineString = ""
-				}
-
-				formattedOutput += child.Html(indentLevel + 1)
-			}
-		}
-		if len(inlineString) > 0 {
-			// split the inline string and append each
-			lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-			appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-			inlineString = ""
-		}
-	} else {
-		// check if everything can fit on one line. if not, figure it out
-		inlineString := i.InnerHtml()
-		lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-		appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-	}
-
-	formattedOutput += closingTag + "\n"
-
-	return formattedOutput
+func (i *Italic) Html(indentLevel int) string {
+	return htmlString(i, indentLevel)
 }
 
-type blockQuote struct {
-	Properties []property
-	Content    []component
+// BlockQuote is a > quote, rendered as <blockquote>.
+type BlockQuote struct {
+	SourceRange
+	Properties []Property
+	Content    []Node
 }
 
-func (bq *blockQuote) InnerHtml() string {
-	var contentString string
-	for _, child := range bq.Content {
-		contentString += child.Raw()
-	}
-	return contentString
+func (bq *BlockQuote) InnerHtml() string {
+	return rawString(bq.Content...)
 }
 
-func (bq *blockQuote) Raw() string {
-	var propertyString string
-	for _, property := range bq.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-	return fmt.Sprintf("<blockquote%s>%s</blockquote>", propertyString, bq.InnerHtml())
+func (bq *BlockQuote) Raw() string {
+	return rawString(bq)
 }
 
-func (bq *blockQuote) Type() ComponentType {
+func (bq *BlockQuote) Type() ComponentType {
 	return Block
 }
 
-func (bq *blockQuote) String() string {
+func (bq *BlockQuote) String() string {
 	return fmt.Sprintf("BlockQuote{Content=[%s]}\n", bq.InnerHtml())
 }
 
-func (bq *blockQuote) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range bq.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<blockquote%s>", propertyString)
-	closingTag := "</blockquote>"
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-
-	formattedOutput := "\n" + indentPrefix + openingTag
-
-	containsBlockElement := slices.ContainsFunc(bq.Content, func(c component) bool {
-		return c.Type() == Block
-	})
-
-	if containsBlockElement {
-		// put child component on new line and indented + 1
-		if len(bq.Content) > 0 && bq.Content[0].Type() == Inline {
-			formattedOutput += "\n"
-		}
-
-		var inlineString string
-		for _, child := range bq.Content {
-			if child.Type() == Inline {
-				inlineString += indentPrefix + INDENT + child.Raw()
-			} else {
-				if len(inlineString) > 0 {
-					// split the inline string and append each
-					lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-					appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-					inlineString = ""
-				}
-
-				formattedOutput += child.Html(indentLevel + 1)
-			}
-		}
-		if len(inlineString) > 0 {
-			// split the inline string and append each
-			lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-			appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-			inlineString = ""
-		}
-		formattedOutput += "\n" + indentPrefix
-	} else {
-		// check if everything can fit on one line. if not, figure it out
-		inlineString := bq.InnerHtml()
-		lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-		appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-	}
-
-	formattedOutput += closingTag + "\n"
-
-	return formattedOutput
+func (bq *BlockQuote) Html(indentLevel int) string {
+	return htmlString(bq, indentLevel)
 }
 
-type listItem struct {
-	Properties []property
-	Component  component
+// ListItem is a single entry of an OrderedList or UnorderedList.
+type ListItem struct {
+	SourceRange
+	Properties []Property
+	Component  Node
+	// Lists and blocks nested in the item
+	Children []Node
+	// Whether the item starts with a [ ] or [x] checkbox, and whether it is checked
+	Task    bool
+	Checked bool
+	// Properties written after the checkbox, rendered on the <input>
+	CheckboxProperties []Property
 }
 
-func (li *listItem) Raw() string {
-	var propertyString string
-	for _, property := range li.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-	return fmt.Sprintf("<li%s>%s</li>", propertyString, li.Component.Raw())
+func (li *ListItem) Raw() string {
+	return rawString(li)
 }
 
-func (li *listItem) Type() ComponentType {
+func (li *ListItem) Type() ComponentType {
 	return Block
 }
 
-func (li *listItem) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range li.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<li%s>\n", propertyString)
-	closingTag := "</li>"
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-
-	formattedOutput := indentPrefix + openingTag
-
-	if li.Component.Type() == Block {
-		formattedOutput += li.Component.Html(indentLevel + 1)
-		formattedOutput += indentPrefix
-	} else {
-		inlineString := strings.Repeat(INDENT, indentLevel+1) + li.Component.Html(indentLevel+1)
-		lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-		appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-	}
-
-	formattedOutput += "\n" + indentPrefix + closingTag + "\n"
-
-	return formattedOutput
+func (li *ListItem) Html(indentLevel int) string {
+	return htmlString(li, indentLevel)
 }
 
-type orderedList struct {
-	Properties []property
-	ListItems  []listItem
+// OrderedList is a numbered list, rendered as <ol>.
+type OrderedList struct {
+	SourceRange
+	Properties []Property
+	ListItems  []ListItem
 	Start      int
 }
 
-func (ol *orderedList) Raw() string {
-	var propertyString string
-	for _, property := range ol.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	var listItemString string
-	for _, item := range ol.ListItems {
-		listItemString += fmt.Sprintf("    %s\n", item.Raw())
-	}
-
-	return fmt.Sprintf("<ol start=\"%d\"%s>\n%s</ol>", ol.Start, propertyString, listItemString)
+func (ol *OrderedList) Raw() string {
+	return rawString(ol)
 }
 
-func (ol *orderedList) Type() ComponentType {
+func (ol *OrderedList) Type() ComponentType {
 	return Block
 }
 
-func (ol *orderedList) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range ol.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<ol%s>", propertyString)
-	closingTag := "</ol>"
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-
-	formattedOutput := "\n" + indentPrefix + openingTag + "\n"
-	for _, item := range ol.ListItems {
-		formattedOutput += item.Html(indentLevel + 1)
-	}
-
-	formattedOutput += indentPrefix + closingTag + "\n"
-
-	return formattedOutput
+func (ol *OrderedList) Html(indentLevel int) string {
+	return htmlString(ol, indentLevel)
 }
 
-type unorderedList struct {
-	Properties []property
-	ListItems  []listItem
+// UnorderedList is a - bulleted list, rendered as <ul>.
+type UnorderedList struct {
+	SourceRange
+	Properties []Property
+	ListItems  []ListItem
 }
 
-func (ul *unorderedList) Raw() string {
-	var propertyString string
-	for _, property := range ul.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	var listItemString string
-	for _, item := range ul.ListItems {
-		listItemString += fmt.Sprintf("    %s\n", item.Raw())
-	}
-
-	return fmt.Sprintf("<ul%s>\n%s</ul>", propertyString, listItemString)
+func (ul *UnorderedList) Raw() string {
+	return rawString(ul)
 }
 
-func (ul *unorderedList) Type() ComponentType {
+func (ul *UnorderedList) Type() ComponentType {
 	return Block
 }
 
-func (ul *unorderedList) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range ul.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<ul%s>", propertyString)
-	closingTag := "</ul>"
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-
-	formattedOutput := "\n" + indentPrefix + openingTag + "\n"
-	for _, item := range ul.ListItems {
-		formattedOutput += item.Html(indentLevel + 1)
-	}
-
-	formattedOutput += indentPrefix + closingTag + "\n"
-
-	return formattedOutput
+func (ul *UnorderedList) Html(indentLevel int) string {
+	return htmlString(ul, indentLevel)
 }
 
-type image struct {
-	Properties []property
+// Image is ![alt](url), rendered as <img>.
+type Image struct {
+	SourceRange
+	Properties []Property
 	ImgUrl     string
 	AltText    string
 }
 
-func (img *image) Raw() string {
-	var propertyString string
-	for _, property := range img.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-	return fmt.Sprintf("<img%s src=\"%s\" alt=\"%s\"/>", propertyString, img.ImgUrl, img.AltText)
+func (img *Image) Raw() string {
+	return rawString(img)
 }
 
-func (img *image) Type() ComponentType {
+func (img *Image) Type() ComponentType {
 	return Block
 }
 
-func (img *image) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range img.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-	tag := fmt.Sprintf("<img%s src=\"%s\" alt=\"%s\"/>", propertyString, img.ImgUrl, img.AltText)
-	formattedOutput := indentPrefix + tag + "\n"
-
-	return formattedOutput
+func (img *Image) Html(indentLevel int) string {
+	return htmlString(img, indentLevel)
 }
 
-type horizontalRule struct {
-	Properties []property
+// HorizontalRule is --- or ___, rendered as <hr/>.
+type HorizontalRule struct {
+	SourceRange
+	Properties []Property
 }
 
-func (hr *horizontalRule) Raw() string {
-	var propertyString string
-	for _, property := range hr.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-	return fmt.Sprintf("<hr%s/>", propertyString)
+func (hr *HorizontalRule) Raw() string {
+	return rawString(hr)
 }
 
-func (hr *horizontalRule) Type() ComponentType {
+func (hr *HorizontalRule) Type() ComponentType {
 	return Block
 }
 
-func (hr *horizontalRule) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range hr.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-	tag := fmt.Sprintf("<hr%s/>", propertyString)
-	formattedOutput := indentPrefix + tag + "\n"
-
-	return formattedOutput
+func (hr *HorizontalRule) Html(indentLevel int) string {
+	return htmlString(hr, indentLevel)
 }
 
-type link struct {
-	Properties []property
+// Link is [content](url) or <url>, rendered as <a>.
+type Link struct {
+	SourceRange
+	Properties []Property
 	Url        string
-	Content    []component
+	Content    []Node
 }
 
-func (l *link) InnerHtml() string {
-	var contentString string
-	for _, child := range l.Content {
-		contentString += child.Raw()
-	}
-	return contentString
+func (l *Link) InnerHtml() string {
+	return rawString(l.Content...)
 }
 
-func (l *link) Raw() string {
-	var propertyString string
-	for _, property := range l.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-	return fmt.Sprintf("<a%s href=\"%s\" target=_blank>%s</a>", propertyString, l.Url, l.InnerHtml())
+func (l *Link) Raw() string {
+	return rawString(l)
 }
 
-func (l *link) Type() ComponentType {
+func (l *Link) Type() ComponentType {
 	return Inline
 }
 
-func (l *link) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range l.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<a%s href=\"%s\" target=_blank>", propertyString, l.Url)
-	closingTag := "</a>"
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-
-	formattedOutput := openingTag
-	containsBlockElement := slices.ContainsFunc(l.Content, func(c component) bool {
-		return c.Type() == Block
-	})
-
-	if containsBlockElement {
-		// put child component on new line and indented + 1
-		if len(l.Content) > 0 && l.Content[0].Type() == Inline {
-			formattedOutput += "\n"
-		}
-
-		var inlineString string
-		for _, child := range l.Content {
-			if child.Type() == Inline {
-				inlineString += indentPrefix + INDENT + child.Raw()
-			} else {
-				if len(inlineString) > 0 {
-					// split the inline string and append each
-					lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-					appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-					inlineString = ""
-				}
-
-				formattedOutput += child.Html(indentLevel + 1)
-			}
-		}
-		if len(inlineString) > 0 {
-			// split the inline string and append each
-			lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-			appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-			inlineString = ""
-		}
-	} else {
-		// check if everything can fit on one line. if not, figure it out
-		inlineString := l.InnerHtml()
-		lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-		appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-	}
-
-	formattedOutput += closingTag + "\n"
-
-	return formattedOutput
+func (l *Link) Html(indentLevel int) string {
+	return htmlString(l, indentLevel)
 }
 
-type button struct {
-	Properties []property
-	Content    []component
+// Button is ~[content](handler), rendered as <button> calling handler on click.
+type Button struct {
+	SourceRange
+	Properties []Property
+	Content    []Node
 	OnClick    string
 }
 
-func (b *button) String() string {
+func (b *Button) String() string {
 	var contentString string
 	for _, child := range b.Content {
 		contentString += fmt.Sprintf("%s ", child)
@@ -804,93 +383,30 @@ func (b *button) String() string {
 	return fmt.Sprintf("Button{OnClick='%s', Content=[%s]}", b.OnClick, strings.TrimSpace(contentString))
 }
 
-func (b *button) InnerHtml() string {
-	var contentString string
-	for _, child := range b.Content {
-		contentString += child.Raw()
-	}
-	return contentString
+func (b *Button) InnerHtml() string {
+	return rawString(b.Content...)
 }
 
-func (b *button) Raw() string {
-	var propertyString string
-	for _, property := range b.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-	return fmt.Sprintf("<button%s onclick=\"%s(this)\">\n    %s\n</button>", propertyString, b.OnClick, b.InnerHtml())
+func (b *Button) Raw() string {
+	return rawString(b)
 }
 
-func (b *button) Type() ComponentType {
+func (b *Button) Type() ComponentType {
 	return Block
 }
 
-func (b *button) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range b.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	var formattedOutput = "\n"
-	var indentPrefix string
-	for range indentLevel {
-		indentPrefix += INDENT
-	}
-
-	openingTag := fmt.Sprintf("<button%s onclick=\"%s(this)\">", propertyString, b.OnClick)
-	closingTag := "</button>"
-
-	formattedOutput += indentPrefix + openingTag
-
-	containsBlockElement := slices.ContainsFunc(b.Content, func(c component) bool {
-		return c.Type() == Block
-	})
-
-	if containsBlockElement {
-		// put child component on new line and indented + 1
-		if len(b.Content) > 0 && b.Content[0].Type() == Inline {
-			formattedOutput += "\n"
-		}
-
-		var inlineString string
-		for _, child := range b.Content {
-			if child.Type() == Inline {
-				inlineString += indentPrefix + INDENT + child.Raw()
-			} else {
-				if len(inlineString) > 0 {
-					// split the inline string and append each
-					lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-					appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-					inlineString = ""
-				}
-
-				formattedOutput += child.Html(indentLevel + 1)
-			}
-		}
-		if len(inlineString) > 0 {
-			// split the inline string and append each
-			lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-			appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-			inlineString = ""
-		}
-		formattedOutput += "\n" + indentPrefix
-	} else {
-		// check if everything can fit on one line. if not, figure it out
-		inlineString := b.InnerHtml()
-		lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-		appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-	}
-
-	formattedOutput += closingTag + "\n"
-
-	return formattedOutput
+func (b *Button) Html(indentLevel int) string {
+	return htmlString(b, indentLevel)
 }
 
-type div struct {
-	Properties []property
-	Children   []component
+// Div is content wrapped in [ ], rendered as <div>.
+type Div struct {
+	SourceRange
+	Properties []Property
+	Children   []Node
 }
 
-func (d *div) String() string {
+func (d *Div) String() string {
 	var contentString string
 	for _, child := range d.Children {
 		contentString += fmt.Sprintf("%s ", child)
@@ -898,111 +414,45 @@ func (d *div) String() string {
 	return fmt.Sprintf("Div{Children=[%s]}", strings.TrimSpace(contentString))
 }
 
-func (d *div) Raw() string {
-	var divString string
-	var propertyString string
-	for _, property := range d.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	if len(d.Children) == 0 {
-		return fmt.Sprintf("<div%s/>", propertyString)
-	}
-
-	divString += fmt.Sprintf("<div%s>\n", propertyString)
-	for _, child := range d.Children {
-		divString += fmt.Sprintf("    %s\n", child.Raw())
-	}
-	divString += "</div>"
-
-	return divString
+func (d *Div) Raw() string {
+	return rawString(d)
 }
 
-func (d *div) Type() ComponentType {
+func (d *Div) Type() ComponentType {
 	return Block
 }
 
-func (d *div) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range d.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<div%s>", propertyString)
-	closingTag := "</div>"
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-
-	formattedOutput := "\n" + indentPrefix + openingTag + "\n"
-
-	for _, child := range d.Children {
-		if child.Type() == Inline {
-			formattedOutput += strings.Repeat(INDENT, indentLevel+1)
-		}
-		formattedOutput += child.Html(indentLevel + 1)
-	}
-
-	formattedOutput += "\n" + indentPrefix + closingTag + "\n"
-	return formattedOutput
+func (d *Div) Html(indentLevel int) string {
+	return htmlString(d, indentLevel)
 }
 
-type nav struct {
-	Properties []property
-	Children   []component
+// Nav is content wrapped in @ @, rendered as <nav>.
+type Nav struct {
+	SourceRange
+	Properties []Property
+	Children   []Node
 }
 
-func (n *nav) Raw() string {
-	var navString string
-	var propertyString string
-	for _, property := range n.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	if len(n.Children) == 0 {
-		return fmt.Sprintf("<nav%s/>", propertyString)
-	}
-
-	navString += fmt.Sprintf("<nav%s>\n", propertyString)
-	for _, child := range n.Children {
-		navString += fmt.Sprintf("    %s\n", child.Raw())
-	}
-	navString += "</nav>"
-
-	return navString
+func (n *Nav) Raw() string {
+	return rawString(n)
 }
 
-func (n *nav) Type() ComponentType {
+func (n *Nav) Type() ComponentType {
 	return Block
 }
 
-func (n *nav) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range n.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<nav%s>", propertyString)
-	closingTag := "</nav>"
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-
-	formattedOutput := "\n" + indentPrefix + openingTag + "\n"
-
-	for _, child := range n.Children {
-		if child.Type() == Inline {
-			formattedOutput += strings.Repeat(INDENT, indentLevel+1)
-		}
-		formattedOutput += child.Html(indentLevel + 1)
-	}
-
-	formattedOutput += "\n" + indentPrefix + closingTag + "\n"
-	return formattedOutput
+func (n *Nav) Html(indentLevel int) string {
+	return htmlString(n, indentLevel)
 }
 
-type span struct {
-	Properties []property
-	Content    []component
+// Span is inline content wrapped in $ $, rendered as <span>.
+type Span struct {
+	SourceRange
+	Properties []Property
+	Content    []Node
 }
 
-func (s *span) String() string {
+func (s *Span) String() string {
 	var contentString string
 	for _, child := range s.Content {
 		contentString += fmt.Sprintf("%s ", child)
@@ -1010,211 +460,190 @@ func (s *span) String() string {
 	return fmt.Sprintf("Span{Content=[%s]}", strings.TrimSpace(contentString))
 }
 
-func (s *span) InnerHtml() string {
-	var contentString string
-	for _, child := range s.Content {
-		contentString += child.Raw()
-	}
-	return contentString
+func (s *Span) InnerHtml() string {
+	return rawString(s.Content...)
 }
 
-func (s *span) Raw() string {
-	var propertyString string
-	for _, property := range s.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	if len(s.Content) == 0 {
-		return fmt.Sprintf("<span%s/>", propertyString)
-	}
-
-	return fmt.Sprintf("<span%s>%s</span>", propertyString, s.InnerHtml())
+func (s *Span) Raw() string {
+	return rawString(s)
 }
 
-func (s *span) Type() ComponentType {
+func (s *Span) Type() ComponentType {
 	return Inline
 }
 
-func (s *span) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range s.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<span%s>", propertyString)
-	closingTag := "</span>"
-
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
-	formattedOutput := openingTag
-
-	containsBlockElement := slices.ContainsFunc(s.Content, func(c component) bool {
-		return c.Type() == Block
-	})
-
-	if containsBlockElement {
-		// put child component on new line and indented + 1
-		if len(s.Content) > 0 && s.Content[0].Type() == Inline {
-			formattedOutput += "\n"
-		}
-
-		var inlineString string
-		for _, child := range s.Content {
-			if child.Type() == Inline {
-				inlineString += indentPrefix + INDENT + child.Raw()
-			} else {
-				if len(inlineString) > 0 {
-					// split the inline string and append each
-					lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-					appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-					inlineString = ""
-				}
-
-				formattedOutput += child.Html(indentLevel + 1)
-			}
+func (s *Span) Html(indentLevel int) string {
+	return htmlString(s, indentLevel)
+}
+
+// CodeBlock is code wrapped in ^^ ^^, rendered as a styled code-block div.
+type CodeBlock struct {
+	SourceRange
+	Properties []Property
+	// Language the code is highlighted as, set with the lang property
+	Language string
+	// Write each line's number in a gutter, set with lines=true
+	LineNumbers bool
+	// Number of the first line, set with the start property, or 0 to count from 1
+	StartLine int
+	// Lines to emphasise by their number, set with the highlight property such as 3-5,9
+	HighlightLines []LineRange
+	// Caption written above the code, such as a filename, set with the title property
+	Title string
+	// File the code was included from, set with the file property
+	Source string
+	// Lines of code separated by line breaks, with tabs expanded to spaces
+	Content string
+}
+
+// LineRange is an inclusive range of line numbers.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// Reports whether line is in any of ranges.
+func inLineRanges(ranges []LineRange, line int) bool {
+	for _, r := range ranges {
+		if line >= r.Start && line <= r.End {
+			return true
 		}
-		if len(inlineString) > 0 {
-			// split the inline string and append each
-			lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-			appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
-			inlineString = ""
-		}
-	} else {
-		// check if everything can fit on one line. if not, figure it out
-		inlineString := s.InnerHtml()
-		lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
-		appendInlineString(inlineString, indentPrefix, lineLength, &formattedOutput)
 	}
+	return false
+}
 
-	formattedOutput += closingTag + "\n"
-
-	return formattedOutput
+func (cb *CodeBlock) Raw() string {
+	return rawString(cb)
 }
 
-type codeBlock struct {
-	Properties []property
-	Content    string
+func (cb *CodeBlock) Type() ComponentType {
+	return Block
 }
 
-func (cb *codeBlock) Raw() string {
-	var codeBlockString string
-	var propertiesString string
-	for _, property := range cb.Properties {
-		propertiesString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
+func (cb *CodeBlock) Html(indentLevel int) string {
+	return htmlString(cb, indentLevel)
+}
 
-	lines := strings.Split(cb.Content, "\\n")
-	codeBlockString += fmt.Sprintf("<div class=\"code-block\"%s>\n", propertiesString)
-	for _, line := range lines {
-		codeBlockString += fmt.Sprintf("    <pre>%s</pre>\n", line)
-	}
-	codeBlockString += "</div>"
+// FencedCodeBlock is code between ``` or ~~~ fences, rendered as <pre><code> with a language-* class when the opening
+// fence names a language.
+type FencedCodeBlock struct {
+	SourceRange
+	Properties []Property
+	Language   string
+	Content    string
+}
 
-	return codeBlockString
+func (fcb *FencedCodeBlock) Raw() string {
+	return rawString(fcb)
 }
 
-func (cb *codeBlock) Type() ComponentType {
+func (fcb *FencedCodeBlock) Type() ComponentType {
 	return Block
 }
 
-func (cb *codeBlock) Html(indentLevel int) string {
-	var propertyString string
-	for _, property := range cb.Properties {
-		propertyString += fmt.Sprintf(" %s=\"%s\"", property.Name, property.Value)
-	}
-
-	openingTag := fmt.Sprintf("<div class=\"code-block\"%s>", propertyString)
-	closingTag := "</div>"
-	indentPrefix := strings.Repeat(INDENT, indentLevel)
+func (fcb *FencedCodeBlock) Html(indentLevel int) string {
+	return htmlString(fcb, indentLevel)
+}
 
-	formattedOutput := "\n" + indentPrefix + openingTag + "\n"
-	lines := strings.Split(cb.Content, "\\n")
-	for _, line := range lines {
-		formattedOutput += indentPrefix + INDENT
-		formattedOutput += fmt.Sprintf("<pre>%s</pre>\n", line)
-	}
+// Alignment is how the cells of a table column are aligned, set by colons in the table's delimiter row.
+type Alignment int
 
-	formattedOutput += indentPrefix + closingTag + "\n"
+const (
+	AlignNone Alignment = iota
+	AlignLeft
+	AlignCenter
+	AlignRight
+)
 
-	return formattedOutput
+// TableCell is a single cell of a TableRow, rendered as <th> in the header row and <td> in the body.
+type TableCell struct {
+	SourceRange
+	Properties []Property
+	Header     bool
+	Align      Alignment
+	Content    []Node
 }
 
-type body struct {
-	Children []component
+func (tc *TableCell) InnerHtml() string {
+	return rawString(tc.Content...)
 }
 
-func (b *body) Raw() string {
-	bodyString := "<body>\n"
-	for _, child := range b.Children {
-		bodyString += fmt.Sprintf("    %s\n", child.Raw())
-	}
-	bodyString += "</body>"
-	return bodyString
+func (tc *TableCell) Raw() string {
+	return rawString(tc)
 }
 
-func (b *body) Type() ComponentType {
+func (tc *TableCell) Type() ComponentType {
 	return Block
 }
 
-func (b *body) Html(indentLevel int) string {
-	formattedOutput := "\n"
-	var indentPrefix string
-	for range indentLevel {
-		indentPrefix += INDENT
-	}
-	formattedOutput += indentPrefix + "<body>"
+func (tc *TableCell) Html(indentLevel int) string {
+	return htmlString(tc, indentLevel)
+}
 
-	if len(b.Children) > 0 && b.Children[0].Type() == Inline {
-		formattedOutput += "\n"
-	}
+// TableRow is a row of a Table, rendered as <tr>.
+type TableRow struct {
+	SourceRange
+	Properties []Property
+	Cells      []TableCell
+}
 
-	for _, child := range b.Children {
-		if child.Type() == Inline {
-			formattedOutput += strings.Repeat(INDENT, indentLevel+1)
-		}
+func (tr *TableRow) Raw() string {
+	return rawString(tr)
+}
 
-		formattedOutput += child.Html(indentLevel + 1)
-	}
+func (tr *TableRow) Type() ComponentType {
+	return Block
+}
 
-	if len(b.Children) > 0 && b.Children[len(b.Children)-1].Type() == Inline {
-		formattedOutput += "\n"
-	}
+func (tr *TableRow) Html(indentLevel int) string {
+	return htmlString(tr, indentLevel)
+}
 
-	formattedOutput += indentPrefix + "</body>"
+// Table is a | pipe | table, rendered as <table> with the header row in <thead> and the other rows in <tbody>.
+type Table struct {
+	SourceRange
+	Properties []Property
+	Header     TableRow
+	Rows       []TableRow
+}
 
-	return formattedOutput
+func (t *Table) Raw() string {
+	return rawString(t)
 }
 
-func appendInlineString(inlineString string, indentPrefix string, lineLength int, formattedOutput *string) {
-	if lineLength < MAX_LENGTH {
-		*formattedOutput += inlineString
-	} else {
-		*formattedOutput += "\n"
-		indentString := indentPrefix + INDENT
+func (t *Table) Type() ComponentType {
+	return Block
+}
 
-		words := strings.Split(inlineString, " ")
-		currentLine := words[0]
-		words = words[1:]
+func (t *Table) Html(indentLevel int) string {
+	return htmlString(t, indentLevel)
+}
 
-		for len(words) > 0 {
-			canAdd := MAX_LENGTH - len(currentLine) - len(indentString)
+// Document is the root of a parsed MDX source.
+type Document struct {
+	Children []Node
+	// Problems found while parsing that didn't stop the document from being parsed
+	Warnings []Warning
+}
 
-			// not sure if I prefer the look of len(currentLine+words[0]) or just len(words[0]) here
-			if len(words[0]) > canAdd {
-				*formattedOutput += indentString + currentLine + "\n"
-				currentLine = ""
-			}
+// Html converts the document into formatted HTML.
+func (d *Document) Html() string {
+	return transformMDX(d.Children)
+}
 
-			if len(currentLine) > 0 {
-				currentLine += " "
-			}
-			currentLine += words[0]
-			words = words[1:]
-		}
+type body struct {
+	SourceRange
+	Children []Node
+}
 
-		if len(currentLine) > 0 {
-			*formattedOutput += indentString + currentLine + "\n"
-		}
+func (b *body) Raw() string {
+	return rawString(b)
+}
 
-		*formattedOutput += indentPrefix
-	}
+func (b *body) Type() ComponentType {
+	return Block
+}
+
+func (b *body) Html(indentLevel int) string {
+	return htmlString(b, indentLevel)
 }
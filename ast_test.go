@@ -4,18 +4,18 @@ import (
 	"testing"
 )
 
-func defaultProps(t *testing.T) []property {
+func defaultProps(t *testing.T) []Property {
 	t.Helper()
 
-	properties := make([]property, 0)
-	properties = append(properties, property{Name: "class", Value: "test"})
-	properties = append(properties, property{Name: "style", Value: "background-color: red"})
+	properties := make([]Property, 0)
+	properties = append(properties, Property{Name: "class", Value: "test"})
+	properties = append(properties, Property{Name: "style", Value: "background-color: red"})
 
 	return properties
 }
 
 func TestAstFragmentHtml(t *testing.T) {
-	fragment := fragment{}
+	fragment := Fragment{}
 	fragmentHtml := fragment.Raw()
 	expected := ""
 	if fragmentHtml != expected {
@@ -31,14 +31,14 @@ func TestAstFragmentHtml(t *testing.T) {
 }
 
 func TestAstHeaderHtml(t *testing.T) {
-	h1 := header{Level: 1, Content: []component{&fragment{Value: "Test"}}}
+	h1 := Heading{Level: 1, Content: []Node{&Fragment{Value: "Test"}}}
 	headerHtml := h1.Raw()
 	expected := "<h1>Test</h1>"
 	if headerHtml != expected {
 		t.Errorf("Header wrong, got=%q", headerHtml)
 	}
 
-	h1 = header{Level: 2, Content: []component{&fragment{Value: "Test2"}}}
+	h1 = Heading{Level: 2, Content: []Node{&Fragment{Value: "Test2"}}}
 	headerHtml = h1.Raw()
 	expected = "<h2>Test2</h2>"
 	if headerHtml != expected {
@@ -46,7 +46,7 @@ func TestAstHeaderHtml(t *testing.T) {
 	}
 
 	properties := defaultProps(t)
-	h1 = header{Level: 6, Content: []component{&fragment{Value: "Test with Props"}}, Properties: properties}
+	h1 = Heading{Level: 6, Content: []Node{&Fragment{Value: "Test with Props"}}, Properties: properties}
 	headerHtml = h1.Raw()
 	expected = "<h6 class=\"test\" style=\"background-color: red\">Test with Props</h6>"
 	if headerHtml != expected {
@@ -55,7 +55,7 @@ func TestAstHeaderHtml(t *testing.T) {
 }
 
 func TestAstParagraphHtml(t *testing.T) {
-	paragraph := paragraph{Content: []component{&fragment{Value: "Paragraph test"}}}
+	paragraph := Paragraph{Content: []Node{&Fragment{Value: "Paragraph test"}}}
 	paragraphHtml := paragraph.Raw()
 	expected := "<p>Paragraph test</p>"
 	if paragraphHtml != expected {
@@ -64,7 +64,7 @@ func TestAstParagraphHtml(t *testing.T) {
 }
 
 func TestAstCodeHtml(t *testing.T) {
-	code := code{Text: "fmt.Printf(\"Hello, world!\n\")"}
+	code := Code{Text: "fmt.Printf(\"Hello, world!\n\")"}
 	codeHtml := code.Raw()
 	expected := "<code>fmt.Printf(\"Hello, world!\n\")</code>"
 	if codeHtml != expected {
@@ -80,7 +80,7 @@ func TestAstCodeHtml(t *testing.T) {
 }
 
 func TestAstBoldHtml(t *testing.T) {
-	bold := bold{Content: []component{&fragment{Value: "stronk"}}}
+	bold := Bold{Content: []Node{&Fragment{Value: "stronk"}}}
 	boldHtml := bold.Raw()
 	expected := "<strong>stronk</strong>"
 	if boldHtml != expected {
@@ -96,7 +96,7 @@ func TestAstBoldHtml(t *testing.T) {
 }
 
 func TestAstItalicHtml(t *testing.T) {
-	italic := italic{Content: []component{&fragment{Value: "italian"}}}
+	italic := Italic{Content: []Node{&Fragment{Value: "italian"}}}
 	italicHtml := italic.Raw()
 	expected := "<em>italian</em>"
 	if italicHtml != expected {
@@ -112,7 +112,7 @@ func TestAstItalicHtml(t *testing.T) {
 }
 
 func TestAstBlockQuoteHtml(t *testing.T) {
-	blockquote := blockQuote{Content: []component{&fragment{Value: "quote"}}}
+	blockquote := BlockQuote{Content: []Node{&Fragment{Value: "quote"}}}
 	blockquoteHtml := blockquote.Raw()
 	expected := "<blockquote>quote</blockquote>"
 	if blockquoteHtml != expected {
@@ -128,7 +128,7 @@ func TestAstBlockQuoteHtml(t *testing.T) {
 }
 
 func TestAstListItemHtml(t *testing.T) {
-	listItem := listItem{Component: &paragraph{Content: []component{&fragment{Value: "Item #1"}}}}
+	listItem := ListItem{Component: &Paragraph{Content: []Node{&Fragment{Value: "Item #1"}}}}
 	listItemHtml := listItem.Raw()
 	expected := "<li><p>Item #1</p></li>"
 	if listItemHtml != expected {
@@ -143,11 +143,27 @@ func TestAstListItemHtml(t *testing.T) {
 	}
 }
 
+func TestAstNestedListHtml(t *testing.T) {
+	nested := &OrderedList{Start: 1, ListItems: []ListItem{{Component: &Paragraph{Content: []Node{&Fragment{Value: "Child"}}}}}}
+	list := UnorderedList{ListItems: []ListItem{{Component: &Paragraph{Content: []Node{&Fragment{Value: "Parent"}}}, Children: []Node{nested}}}}
+
+	expected := "<ul>\n    <li><p>Parent</p><ol start=\"1\">\n    <li><p>Child</p></li>\n</ol></li>\n</ul>"
+	if actual := list.Raw(); actual != expected {
+		t.Errorf("Nested list wrong, expected=%q, got=%q", expected, actual)
+	}
+
+	document := &Document{Children: []Node{&list}}
+	expected = "\n    <div>\n        <ul>\n            <li>\n                <p>Parent</p>\n                <ol>\n                    <li>\n                        <p>Child</p>\n                    </li>\n                </ol>\n            </li>\n        </ul>\n    </div>\n"
+	if actual := document.Html(); actual != expected {
+		t.Errorf("Nested list wrong, expected=%q, got=%q", expected, actual)
+	}
+}
+
 func TestAstOrderedListHtml(t *testing.T) {
-	listItem1 := listItem{Component: &paragraph{Content: []component{&fragment{Value: "Item #1"}}}}
-	listItem2 := listItem{Component: &paragraph{Content: []component{&fragment{Value: "Item #2"}}}}
-	listItems := []listItem{listItem1, listItem2}
-	list := orderedList{ListItems: listItems, Start: 1}
+	listItem1 := ListItem{Component: &Paragraph{Content: []Node{&Fragment{Value: "Item #1"}}}}
+	listItem2 := ListItem{Component: &Paragraph{Content: []Node{&Fragment{Value: "Item #2"}}}}
+	listItems := []ListItem{listItem1, listItem2}
+	list := OrderedList{ListItems: listItems, Start: 1}
 	listHtml := list.Raw()
 	expected := "<ol start=\"1\">\n    <li><p>Item #1</p></li>\n    <li><p>Item #2</p></li>\n</ol>"
 	if listHtml != expected {
@@ -164,10 +180,10 @@ func TestAstOrderedListHtml(t *testing.T) {
 }
 
 func TestAstUnorderedListHtml(t *testing.T) {
-	listItem1 := listItem{Component: &paragraph{Content: []component{&fragment{Value: "Item #1"}}}}
-	listItem2 := listItem{Component: &paragraph{Content: []component{&fragment{Value: "Item #2"}}}}
-	listItems := []listItem{listItem1, listItem2}
-	list := unorderedList{ListItems: listItems}
+	listItem1 := ListItem{Component: &Paragraph{Content: []Node{&Fragment{Value: "Item #1"}}}}
+	listItem2 := ListItem{Component: &Paragraph{Content: []Node{&Fragment{Value: "Item #2"}}}}
+	listItems := []ListItem{listItem1, listItem2}
+	list := UnorderedList{ListItems: listItems}
 	listHtml := list.Raw()
 	expected := "<ul>\n    <li><p>Item #1</p></li>\n    <li><p>Item #2</p></li>\n</ul>"
 	if listHtml != expected {
@@ -183,7 +199,7 @@ func TestAstUnorderedListHtml(t *testing.T) {
 }
 
 func TestAstImageHtml(t *testing.T) {
-	img := image{ImgUrl: "https://img.pokemondb.net/artwork/avif/regirock.avif", AltText: "Reginald"}
+	img := Image{ImgUrl: "https://img.pokemondb.net/artwork/avif/regirock.avif", AltText: "Reginald"}
 	imgHtml := img.Raw()
 	expected := "<img src=\"https://img.pokemondb.net/artwork/avif/regirock.avif\" alt=\"Reginald\"/>"
 	if imgHtml != expected {
@@ -196,7 +212,7 @@ func TestAstImageHtml(t *testing.T) {
 }
 
 func TestAstHorizontalRuleHtml(t *testing.T) {
-	rule := horizontalRule{}
+	rule := HorizontalRule{}
 	ruleHtml := rule.Raw()
 	expected := "<hr/>"
 	if ruleHtml != expected {
@@ -212,7 +228,7 @@ func TestAstHorizontalRuleHtml(t *testing.T) {
 }
 
 func TestAstLinkHtml(t *testing.T) {
-	link := link{Url: "https://google.com", Content: []component{&fragment{Value: "Google"}}}
+	link := Link{Url: "https://google.com", Content: []Node{&Fragment{Value: "Google"}}}
 	linkHtml := link.Raw()
 	expected := "<a href=\"https://google.com\" target=_blank>Google</a>"
 	if linkHtml != expected {
@@ -228,7 +244,7 @@ func TestAstLinkHtml(t *testing.T) {
 }
 
 func TestAstButtonHtml(t *testing.T) {
-	button := button{OnClick: "handleClick", Content: []component{&paragraph{Content: []component{&fragment{Value: "Click Me"}}}}}
+	button := Button{OnClick: "handleClick", Content: []Node{&Paragraph{Content: []Node{&Fragment{Value: "Click Me"}}}}}
 	buttonHtml := button.Raw()
 	expected := "<button onclick=\"handleClick(this)\">\n    <p>Click Me</p>\n</button>"
 	if buttonHtml != expected {
@@ -244,7 +260,7 @@ func TestAstButtonHtml(t *testing.T) {
 }
 
 func TestAstDivHtml(t *testing.T) {
-	emptyDiv := div{}
+	emptyDiv := Div{}
 	divHtml := emptyDiv.Raw()
 	expected := "<div/>"
 	if divHtml != expected {
@@ -252,15 +268,15 @@ func TestAstDivHtml(t *testing.T) {
 	}
 
 	properties := defaultProps(t)
-	propertyDiv := div{Properties: properties}
+	propertyDiv := Div{Properties: properties}
 	divHtml = propertyDiv.Raw()
 	expected = "<div class=\"test\" style=\"background-color: red\"/>"
 	if divHtml != expected {
 		t.Errorf("Property Div wrong, got=%q", divHtml)
 	}
 
-	p := &paragraph{Content: []component{&fragment{Value: "child"}}}
-	childDiv := div{Children: []component{p}}
+	p := &Paragraph{Content: []Node{&Fragment{Value: "child"}}}
+	childDiv := Div{Children: []Node{p}}
 	divHtml = childDiv.Raw()
 	expected = "<div>\n    <p>child</p>\n</div>"
 	if divHtml != expected {
@@ -269,7 +285,7 @@ func TestAstDivHtml(t *testing.T) {
 }
 
 func TestAstNavHtml(t *testing.T) {
-	nav := nav{}
+	nav := Nav{}
 	navHtml := nav.Raw()
 	expected := "<nav/>"
 	if navHtml != expected {
@@ -283,7 +299,7 @@ func TestAstNavHtml(t *testing.T) {
 		t.Errorf("Nav properties wrong, got=%q", navHtml)
 	}
 
-	nav.Children = []component{&link{Url: "https://test.com", Content: []component{&fragment{Value: "Test"}}}}
+	nav.Children = []Node{&Link{Url: "https://test.com", Content: []Node{&Fragment{Value: "Test"}}}}
 	navHtml = nav.Raw()
 	expected = "<nav class=\"test\" style=\"background-color: red\">\n    <a href=\"https://test.com\" target=_blank>Test</a>\n</nav>"
 	if navHtml != expected {
@@ -292,14 +308,14 @@ func TestAstNavHtml(t *testing.T) {
 }
 
 func TestAstSpanHtml(t *testing.T) {
-	span := span{}
+	span := Span{}
 	spanHtml := span.Raw()
 	expected := "<span/>"
 	if spanHtml != expected {
 		t.Errorf("Span wrong, got=%q", spanHtml)
 	}
 
-	span.Content = []component{&paragraph{Content: []component{&fragment{Value: "Hello"}}}}
+	span.Content = []Node{&Paragraph{Content: []Node{&Fragment{Value: "Hello"}}}}
 	spanHtml = span.Raw()
 	expected = "<span><p>Hello</p></span>"
 	if spanHtml != expected {
@@ -308,8 +324,8 @@ func TestAstSpanHtml(t *testing.T) {
 }
 
 func TestAstCodeBlockHtml(t *testing.T) {
-	content := `package main\n\nimport "fmt"\n\nfunc main() {\n    fmt.Println("Hello, world!")\n}`
-	codeBlock := codeBlock{Content: content}
+	content := "package main\n\nimport \"fmt\"\n\nfunc main() {\n    fmt.Println(\"Hello, world!\")\n}"
+	codeBlock := CodeBlock{Content: content}
 	codeBlockHtml := codeBlock.Raw()
 	expected := `<div class="code-block">
     <pre>package main</pre>
@@ -325,3 +341,327 @@ func TestAstCodeBlockHtml(t *testing.T) {
 		t.Errorf("CodeBlock wrong\ngot=     %q\nexpected=%q", codeBlockHtml, expected)
 	}
 }
+
+func TestAstCodeBlockHighlighting(t *testing.T) {
+	codeBlock := CodeBlock{Language: "go", Content: "x := \"a<b\"\n/* c\nd */"}
+	expected := `<div class="code-block">
+    <pre>x <span class="hl-operator">:=</span> <span class="hl-string">"a&lt;b"</span></pre>
+    <pre><span class="hl-comment">/* c</span></pre>
+    <pre><span class="hl-comment">d */</span></pre>
+</div>`
+	if actual := codeBlock.Raw(); actual != expected {
+		t.Errorf("Highlighted CodeBlock wrong\ngot=     %q\nexpected=%q", actual, expected)
+	}
+
+	// highlighting escapes the code even when raw HTML is allowed
+	w := &RenderWriter{renderer: &HtmlRenderer{AllowRawHtml: true}}
+	if actual := w.InlineString(&codeBlock); actual != expected {
+		t.Errorf("Highlighted raw CodeBlock wrong\ngot=     %q\nexpected=%q", actual, expected)
+	}
+
+	unknown := CodeBlock{Language: "cobol", Content: "a < b"}
+	expected = "<div class=\"code-block\">\n    <pre>a &lt; b</pre>\n</div>"
+	if actual := unknown.Raw(); actual != expected {
+		t.Errorf("CodeBlock in unknown language wrong\ngot=     %q\nexpected=%q", actual, expected)
+	}
+}
+
+func TestAstCodeBlockLineOptions(t *testing.T) {
+	codeBlock := CodeBlock{
+		LineNumbers:    true,
+		StartLine:      40,
+		HighlightLines: []LineRange{{Start: 41, End: 41}},
+		Title:          "<main>.go",
+		Content:        "a\nb < c\nd",
+	}
+	expected := `<div class="code-block">
+    <div class="code-block-title">&lt;main&gt;.go</div>
+    <pre><span class="line-number">40</span>a</pre>
+    <pre class="highlighted"><span class="line-number">41</span>b &lt; c</pre>
+    <pre><span class="line-number">42</span>d</pre>
+</div>`
+	if actual := codeBlock.Raw(); actual != expected {
+		t.Errorf("CodeBlock with line options wrong\ngot=     %q\nexpected=%q", actual, expected)
+	}
+
+	// lines are counted from 1 when there's no start line
+	codeBlock = CodeBlock{Language: "go", HighlightLines: []LineRange{{Start: 1, End: 2}}, Content: "x\ny\nz"}
+	expected = "\n    <div>\n        <div class=\"code-block\">\n            <pre class=\"highlighted\">x</pre>\n" +
+		"            <pre class=\"highlighted\">y</pre>\n            <pre>z</pre>\n        </div>\n    </div>\n"
+	if actual := (&Document{Children: []Node{&codeBlock}}).Html(); actual != expected {
+		t.Errorf("CodeBlock with highlighted lines wrong\ngot=     %q\nexpected=%q", actual, expected)
+	}
+}
+
+func TestAstEscaping(t *testing.T) {
+	inputs := map[Node]string{
+		&Fragment{Value: "a < b & c > d"}:                                                                                     "a &lt; b &amp; c &gt; d",
+		&Fragment{Value: "Tom & Jerry&nbsp;&#169;&#xA9; &copy=1"}:                                                             "Tom &amp; Jerry&nbsp;&#169;&#xA9; &amp;copy=1",
+		&Fragment{Value: "it's \"quoted\""}:                                                                                   "it's \"quoted\"",
+		&Code{Text: "if a < b && c > d { s := \"&lt;\" }"}:                                                                    "<code>if a &lt; b &amp;&amp; c &gt; d { s := \"&amp;lt;\" }</code>",
+		&CodeBlock{Content: "<b>&amp;</b>"}:                                                                                   "<div class=\"code-block\">\n    <pre>&lt;b&gt;&amp;amp;&lt;/b&gt;</pre>\n</div>",
+		&Image{ImgUrl: "a.png?x=1&y=\"2\"", AltText: "\"A\" & <B>"}:                                                           "<img src=\"a.png?x=1&amp;y=&#34;2&#34;\" alt=\"&#34;A&#34; &amp; &lt;B&gt;\"/>",
+		&Link{Url: "/search?q=a&b='c'", Content: []Node{&Fragment{Value: "<go>"}}}:                                            "<a href=\"/search?q=a&amp;b=&#39;c&#39;\" target=_blank>&lt;go&gt;</a>",
+		&Button{OnClick: "go\" onmouseover=\"x", Content: []Node{&Fragment{Value: "B"}}}:                                      "<button onclick=\"go&#34; onmouseover=&#34;x(this)\">\n    B\n</button>",
+		&Span{Properties: []Property{{Name: "title", Value: "say \"hi\" & 'bye'"}}, Content: []Node{&Fragment{Value: "x"}}}:   "<span title=\"say &#34;hi&#34; &amp; &#39;bye&#39;\">x</span>",
+		&Span{Properties: []Property{{Name: "a\"b", Value: "c"}, {Name: "onclick x", Value: "y"}, {Name: "id", Value: "ok"}}}: "<span id=\"ok\"/>",
+	}
+
+	for node, expected := range inputs {
+		actual := node.Raw()
+		if actual != expected {
+			t.Errorf("Escaping wrong for %T, expected=%q, got=%q", node, expected, actual)
+		}
+	}
+}
+
+func TestAstAllowRawHtml(t *testing.T) {
+	div := &Div{Children: []Node{
+		&Paragraph{Properties: []Property{{Name: "title", Value: "\"x\""}}, Content: []Node{&Fragment{Value: "<b>bold</b> &"}}},
+		&CodeBlock{Content: "<pre style=\"display: inline\">^</pre>^"},
+	}}
+
+	w := &RenderWriter{renderer: &HtmlRenderer{AllowRawHtml: true}}
+	actual := w.InlineString(div)
+	expected := "<div>\n    <p title=\"&#34;x&#34;\"><b>bold</b> &</p>\n    <div class=\"code-block\">\n    <pre><pre style=\"display: inline\">^</pre>^</pre>\n</div>\n</div>"
+	if actual != expected {
+		t.Errorf("Raw HTML wrong, expected=%q, got=%q", expected, actual)
+	}
+}
+
+func TestAstSafeMode(t *testing.T) {
+	tests := []struct {
+		node     Node
+		safe     *SafeOptions
+		expected string
+	}{
+		{
+			&Paragraph{Properties: []Property{{Name: "onmouseover", Value: "alert(1)"}, {Name: "class", Value: "a"}, {Name: "style", Value: "color: red"}}, Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{},
+			"<p class=\"a\">x</p>",
+		},
+		{
+			&Paragraph{Properties: []Property{{Name: "data-id", Value: "1"}, {Name: "aria-label", Value: "y"}}, Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{},
+			"<p data-id=\"1\" aria-label=\"y\">x</p>",
+		},
+		{
+			&Paragraph{Properties: []Property{{Name: "style", Value: "color: red"}, {Name: "class", Value: "a"}}, Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{AllowedAttributes: []string{"style"}},
+			"<p style=\"color: red\">x</p>",
+		},
+		{
+			&Link{Url: "javascript:alert(1)", Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{},
+			"<a href=\"#\" target=_blank>x</a>",
+		},
+		{
+			&Link{Url: " Java\tScript:alert(1)", Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{},
+			"<a href=\"#\" target=_blank>x</a>",
+		},
+		{
+			&Link{Url: "https://example.com/a:b", Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{},
+			"<a href=\"https://example.com/a:b\" target=_blank>x</a>",
+		},
+		{
+			&Link{Url: "/about?at=10:30", Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{},
+			"<a href=\"/about?at=10:30\" target=_blank>x</a>",
+		},
+		{
+			&Link{Url: "mailto:a@b.com", Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{AllowedSchemes: []string{"https"}},
+			"<a href=\"#\" target=_blank>x</a>",
+		},
+		{
+			&Link{Url: "/", Properties: []Property{{Name: "href", Value: "javascript:alert(1)"}}, Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{AllowedAttributes: []string{"href"}},
+			"<a href=\"/\" target=_blank>x</a>",
+		},
+		{
+			&Image{ImgUrl: "data:image/svg+xml,<svg onload=alert(1)>", AltText: "x"},
+			&SafeOptions{},
+			"",
+		},
+		{
+			&Button{OnClick: "handleClick", Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{},
+			"<button>\n    x\n</button>",
+		},
+		{
+			&Button{OnClick: "app.handleClick", Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{AllowedHandlers: []string{"handleClick", "app.handleClick"}},
+			"<button onclick=\"app.handleClick(this)\">\n    x\n</button>",
+		},
+		{
+			&Button{OnClick: "alert", Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{AllowedHandlers: []string{"handleClick"}},
+			"<button>\n    x\n</button>",
+		},
+		{
+			&Button{OnClick: "alert(1);x", Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{},
+			"<button>\n    x\n</button>",
+		},
+		{
+			&Button{OnClick: "handleClick", Content: []Node{&Fragment{Value: "x"}}},
+			&SafeOptions{DisableButtons: true},
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		w := &RenderWriter{renderer: &HtmlRenderer{Safe: test.safe}}
+		actual := w.InlineString(test.node)
+		if actual != test.expected {
+			t.Errorf("Safe mode wrong, expected=%q, got=%q", test.expected, actual)
+		}
+	}
+}
+
+func TestAstSafeModeIgnoresRawHtml(t *testing.T) {
+	w := &RenderWriter{renderer: &HtmlRenderer{AllowRawHtml: true, Safe: &SafeOptions{}}}
+	actual := w.InlineString(&Fragment{Value: "<script>alert(1)</script>"})
+	expected := "&lt;script&gt;alert(1)&lt;/script&gt;"
+	if actual != expected {
+		t.Errorf("Raw HTML wrong, expected=%q, got=%q", expected, actual)
+	}
+}
+
+func TestAstDefaultProperties(t *testing.T) {
+	renderer := &HtmlRenderer{DefaultProperties: map[string][]Property{
+		"h2":        {{Name: "class", Value: "section-title"}},
+		"a":         {{Name: "rel", Value: "noopener"}, {Name: "class", Value: "link"}},
+		"codeBlock": {{Name: "class", Value: "theme-dark"}},
+	}}
+
+	tests := []struct {
+		node     Node
+		expected string
+	}{
+		{
+			&Heading{Level: 2, Properties: []Property{{Name: "class", Value: "intro"}}, Content: []Node{&Fragment{Value: "Hi"}}},
+			"<h2 class=\"section-title intro\">Hi</h2>",
+		},
+		{
+			&Heading{Level: 1, Content: []Node{&Fragment{Value: "Hi"}}},
+			"<h1>Hi</h1>",
+		},
+		{
+			&Link{Url: "/a", Properties: []Property{{Name: "rel", Value: "external"}}, Content: []Node{&Fragment{Value: "a"}}},
+			"<a rel=\"external\" class=\"link\" href=\"/a\" target=_blank>a</a>",
+		},
+		{
+			&CodeBlock{Properties: []Property{{Name: "class", Value: "wide"}}, Content: "x"},
+			"<div class=\"theme-dark code-block wide\">\n    <pre>x</pre>\n</div>",
+		},
+	}
+
+	for _, test := range tests {
+		w := &RenderWriter{renderer: renderer}
+		actual := w.InlineString(test.node)
+		if actual != test.expected {
+			t.Errorf("Default properties wrong, expected=%q, got=%q", test.expected, actual)
+		}
+	}
+}
+
+func TestAstTaskListHtml(t *testing.T) {
+	todo := ListItem{Task: true, Component: &Paragraph{Content: []Node{&Fragment{Value: "Todo"}}}}
+	done := ListItem{Task: true, Checked: true, Component: &Paragraph{Content: []Node{&Fragment{Value: "Done"}}}}
+	enabled := ListItem{
+		Task:               true,
+		CheckboxProperties: []Property{{Name: "enabled"}, {Name: "name", Value: "step"}},
+		Component:          &Paragraph{Content: []Node{&Fragment{Value: "Enabled"}}},
+	}
+	list := UnorderedList{ListItems: []ListItem{todo, done, enabled}}
+
+	expected := "<ul>\n" +
+		"    <li class=\"task-list-item\"><input type=\"checkbox\" disabled/><p>Todo</p></li>\n" +
+		"    <li class=\"task-list-item\"><input type=\"checkbox\" disabled checked/><p>Done</p></li>\n" +
+		"    <li class=\"task-list-item\"><input type=\"checkbox\" name=\"step\"/><p>Enabled</p></li>\n" +
+		"</ul>"
+	if actual := list.Raw(); actual != expected {
+		t.Errorf("Task list wrong, expected=%q, got=%q", expected, actual)
+	}
+
+	document := &Document{Children: []Node{&UnorderedList{ListItems: []ListItem{done}}}}
+	expected = "\n    <div>\n        <ul>\n            <li class=\"task-list-item\">\n                <input type=\"checkbox\" disabled checked/>\n                <p>Done</p>\n            </li>\n        </ul>\n    </div>\n"
+	if actual := document.Html(); actual != expected {
+		t.Errorf("Task list wrong, expected=%q, got=%q", expected, actual)
+	}
+
+	renderer := &HtmlRenderer{
+		Safe:              &SafeOptions{},
+		DefaultProperties: map[string][]Property{"input": {{Name: "enabled"}}},
+	}
+	w := &RenderWriter{renderer: renderer}
+	expected = "<li class=\"task-list-item\"><input type=\"checkbox\" checked/><p>Done</p></li>"
+	if actual := w.InlineString(&done); actual != expected {
+		t.Errorf("Task list item with default properties wrong, expected=%q, got=%q", expected, actual)
+	}
+}
+
+func TestAstTableHtml(t *testing.T) {
+	cell := func(header bool, align Alignment, value string) TableCell {
+		return TableCell{Header: header, Align: align, Content: []Node{&Fragment{Value: value}}}
+	}
+	table := &Table{
+		Properties: []Property{{Name: "class", Value: "prices"}},
+		Header:     TableRow{Cells: []TableCell{cell(true, AlignNone, "Fruit"), cell(true, AlignRight, "Price")}},
+		Rows: []TableRow{
+			{Properties: []Property{{Name: "class", Value: "total"}}, Cells: []TableCell{cell(false, AlignNone, "Apple"), cell(false, AlignRight, "1")}},
+		},
+	}
+
+	expected := "<table class=\"prices\"><thead><tr><th>Fruit</th><th align=\"right\">Price</th></tr></thead>" +
+		"<tbody><tr class=\"total\"><td>Apple</td><td align=\"right\">1</td></tr></tbody></table>"
+	if actual := table.Raw(); actual != expected {
+		t.Errorf("Table wrong, expected=%q, got=%q", expected, actual)
+	}
+
+	document := &Document{Children: []Node{table}}
+	expected = "\n    <div>\n        <table class=\"prices\">\n            <thead>\n                <tr>\n" +
+		"                    <th>Fruit</th>\n                    <th align=\"right\">Price</th>\n" +
+		"                </tr>\n            </thead>\n            <tbody>\n                <tr class=\"total\">\n" +
+		"                    <td>Apple</td>\n                    <td align=\"right\">1</td>\n" +
+		"                </tr>\n            </tbody>\n        </table>\n    </div>\n"
+	if actual := document.Html(); actual != expected {
+		t.Errorf("Table wrong, expected=%q, got=%q", expected, actual)
+	}
+
+	table.Rows = nil
+	expected = "<table class=\"prices\"><thead><tr><th>Fruit</th><th align=\"right\">Price</th></tr></thead></table>"
+	if actual := table.Raw(); actual != expected {
+		t.Errorf("Table without body wrong, expected=%q, got=%q", expected, actual)
+	}
+}
+
+func TestAstFencedCodeBlockHtml(t *testing.T) {
+	codeBlock := &FencedCodeBlock{
+		Properties: []Property{{Name: "class", Value: "example"}},
+		Language:   "go",
+		Content:    "if a < b {\n\n\treturn\n}",
+	}
+
+	expected := "<pre class=\"example\"><code class=\"language-go\">if a &lt; b {\n\n\treturn\n}</code></pre>"
+	if actual := codeBlock.Raw(); actual != expected {
+		t.Errorf("FencedCodeBlock wrong, expected=%q, got=%q", expected, actual)
+	}
+
+	// blank lines in the code are written as is
+	document := &Document{Children: []Node{&FencedCodeBlock{Content: "a\n\n\nb"}}}
+	expected = "\n    <div>\n        <pre><code>a\n\n\nb</code></pre>\n    </div>\n"
+	if actual := document.Html(); actual != expected {
+		t.Errorf("FencedCodeBlock wrong, expected=%q, got=%q", expected, actual)
+	}
+
+	renderer := &HtmlRenderer{DefaultProperties: map[string][]Property{"pre": {{Name: "class", Value: "theme-dark"}}}}
+	w := &RenderWriter{renderer: renderer}
+	expected = "<pre class=\"theme-dark example\"><code class=\"language-go\">if a &lt; b {\n\n\treturn\n}</code></pre>"
+	if actual := w.InlineString(codeBlock); actual != expected {
+		t.Errorf("FencedCodeBlock with default properties wrong, expected=%q, got=%q", expected, actual)
+	}
+}
@@ -0,0 +1,103 @@
+package mdx
+
+import (
+	"strings"
+)
+
+var codeReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+var urlReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&#34;", "'", "&#39;")
+
+// Escapes s for use as the text content of an element.
+// Character references already in s, such as &nbsp; or &#169;, are kept so that authors can still write them.
+func escapeText(s string) string {
+	return escapeHtml(s, false)
+}
+
+// Escapes s for use as a quoted attribute value, keeping character references like escapeText.
+func escapeAttribute(s string) string {
+	return escapeHtml(s, true)
+}
+
+// Escapes s for use as the content of <code> or <pre>, where every character is shown exactly as written.
+func escapeCode(s string) string {
+	return codeReplacer.Replace(s)
+}
+
+// Escapes s for use as a quoted href or src attribute. Every '&' is escaped, since query strings such as
+// ?a=1&copy=2 aren't meant as character references.
+func escapeUrl(s string) string {
+	return urlReplacer.Replace(s)
+}
+
+func escapeHtml(s string, quotes bool) string {
+	if !strings.ContainsAny(s, "&<>\"'") {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '&' && !isCharacterReference(s[i:]):
+			sb.WriteString("&amp;")
+		case ch == '<':
+			sb.WriteString("&lt;")
+		case ch == '>':
+			sb.WriteString("&gt;")
+		case ch == '"' && quotes:
+			sb.WriteString("&#34;")
+		case ch == '\'' && quotes:
+			sb.WriteString("&#39;")
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return sb.String()
+}
+
+// Reports whether s starts with a character reference such as &amp;, &#169; or &#xA9;.
+func isCharacterReference(s string) bool {
+	end := strings.IndexByte(s, ';')
+	if end < 2 || end > 32 {
+		return false
+	}
+
+	name := s[1:end]
+	isDigits := func(digits string, hex bool) bool {
+		for _, ch := range digits {
+			if !(isDigit(ch) || hex && ('a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F')) {
+				return false
+			}
+		}
+		return len(digits) > 0
+	}
+
+	switch {
+	case strings.HasPrefix(name, "#x") || strings.HasPrefix(name, "#X"):
+		return isDigits(name[2:], true)
+	case strings.HasPrefix(name, "#"):
+		return isDigits(name[1:], false)
+	}
+
+	for i, ch := range name {
+		isAsciiLetter := 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+		if !(isAsciiLetter || i > 0 && isDigit(ch)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Reports whether name can be written as an attribute name without breaking the surrounding tag.
+func isValidAttributeName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+
+	for _, ch := range name {
+		if ch <= ' ' || ch == 0x7f || strings.ContainsRune("\"'<>/=`", ch) {
+			return false
+		}
+	}
+	return true
+}
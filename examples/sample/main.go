@@ -24,6 +24,8 @@ func main() {
 				"rel":  "stylesheet",
 				"href": "https://fonts.googleapis.com/css2?family=Fira+Code",
 			}},
+		// sample.mdx uses HTML inside code blocks to show MDX syntax that would otherwise be parsed
+		AllowRawHtml: true,
 	}
 
 	n, err := mdx.Generate(config)
@@ -1,6 +1,7 @@
 package mdx
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -12,15 +13,21 @@ type GeneratorConfig struct {
 	InputFilename  string
 	OutputFilename string
 	Links          []map[string]string
+	// Write text and code block content without escaping, see HtmlRenderer.AllowRawHtml
+	AllowRawHtml bool
+	// Properties added to every element of a kind, see HtmlRenderer.DefaultProperties
+	DefaultProperties map[string][]Property
+	// Styles for highlighted code blocks, written to a <style> in the head
+	Theme Theme
 }
 
-func transformMDX(elements []component) string {
-	content := &div{Children: elements}
-	htmlString := strings.ReplaceAll(content.Html(1), "\n\n", "\n")
-	return htmlString
+func transformMDX(elements []Node) string {
+	var sb strings.Builder
+	renderHtml(context.Background(), &sb, &Div{Children: elements}, 1, &HtmlRenderer{})
+	return sb.String()
 }
 
-func generateHtml(elements []component, config *GeneratorConfig) (int, error) {
+func generateHtml(elements []Node, config *GeneratorConfig) (int, error) {
 	file, fileErr := os.OpenFile(config.OutputFilename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0777)
 	if fileErr != nil {
 		log.Println(fileErr.Error())
@@ -37,26 +44,31 @@ func generateHtml(elements []component, config *GeneratorConfig) (int, error) {
 
 	if len(config.Title) > 0 {
 		file.WriteString(fmt.Sprintf(`
-        <title>%s</title>`, config.Title))
+        <title>%s</title>`, escapeText(config.Title)))
 	}
 
 	for _, link := range config.Links {
 		linkString := "\n        <link "
 		for name, value := range link {
-			if len(value) > 0 {
-				linkString += fmt.Sprintf("%s=\"%s\" ", name, value)
+			if len(value) > 0 && isValidAttributeName(name) {
+				linkString += fmt.Sprintf("%s=\"%s\" ", name, escapeAttribute(value))
 			}
 		}
 		linkString += ">"
 		file.WriteString(linkString)
 	}
 
+	if len(config.Theme) > 0 {
+		css := strings.ReplaceAll(strings.TrimSuffix(config.Theme.CSS(), "\n"), "\n", "\n            ")
+		file.WriteString("\n        <style>\n            " + css + "\n        </style>")
+	}
+
 	file.WriteString(`
-    </head>
-`)
+    </head>`)
 
 	body := &body{Children: elements}
-	n, writeErr := file.WriteString(strings.ReplaceAll(body.Html(1), "\n\n", "\n"))
+	renderer := &HtmlRenderer{AllowRawHtml: config.AllowRawHtml, DefaultProperties: config.DefaultProperties}
+	n, writeErr := renderHtml(context.Background(), file, body, 1, renderer)
 	if writeErr != nil {
 		log.Printf(writeErr.Error())
 		return n, writeErr
@@ -0,0 +1,283 @@
+package mdx
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// TokenClass classifies a run of highlighted code. Highlighted code wraps each token in a <span> with the class
+// "hl-" followed by its TokenClass, such as "hl-keyword".
+type TokenClass string
+
+const (
+	TokenText        TokenClass = ""
+	TokenKeyword     TokenClass = "keyword"
+	TokenType        TokenClass = "type"
+	TokenBuiltin     TokenClass = "builtin"
+	TokenLiteral     TokenClass = "literal"
+	TokenString      TokenClass = "string"
+	TokenNumber      TokenClass = "number"
+	TokenComment     TokenClass = "comment"
+	TokenOperator    TokenClass = "operator"
+	TokenPunctuation TokenClass = "punctuation"
+	TokenProperty    TokenClass = "property"
+	TokenVariable    TokenClass = "variable"
+	TokenTag         TokenClass = "tag"
+	TokenAttribute   TokenClass = "attribute"
+)
+
+// CodeToken is a run of code with a single class.
+type CodeToken struct {
+	Class TokenClass
+	Text  string
+}
+
+// CodeLexer splits code into tokens for highlighting. The text of the tokens joined together must be the code.
+type CodeLexer interface {
+	Tokenize(code string) []CodeToken
+}
+
+// CodeLexerFunc adapts a function to a CodeLexer.
+type CodeLexerFunc func(code string) []CodeToken
+
+func (f CodeLexerFunc) Tokenize(code string) []CodeToken {
+	return f(code)
+}
+
+var lexerRegistry = struct {
+	sync.RWMutex
+	lexers map[string]CodeLexer
+}{lexers: defaultLexers()}
+
+// RegisterLexer makes lexer highlight code blocks in each of the named languages, replacing any lexer already
+// registered for them. Names are matched case-insensitively.
+func RegisterLexer(lexer CodeLexer, names ...string) {
+	lexerRegistry.Lock()
+	defer lexerRegistry.Unlock()
+
+	for _, name := range names {
+		lexerRegistry.lexers[strings.ToLower(name)] = lexer
+	}
+}
+
+// LookupLexer returns the lexer registered for language.
+func LookupLexer(language string) (CodeLexer, bool) {
+	lexerRegistry.RLock()
+	defer lexerRegistry.RUnlock()
+
+	lexer, ok := lexerRegistry.lexers[strings.ToLower(language)]
+	return lexer, ok
+}
+
+// Highlight returns code as HTML with each token wrapped in a classed <span>, or false if no lexer is registered for
+// language. The code is escaped.
+func Highlight(code, language string) (string, bool) {
+	lexer, ok := LookupLexer(language)
+	if !ok {
+		return "", false
+	}
+	return strings.Join(highlightLines(code, lexer), "\n"), true
+}
+
+// Returns the highlighted HTML for each line of code. Tokens spanning several lines are split so that every line
+// opens and closes its own spans.
+func highlightLines(code string, lexer CodeLexer) []string {
+	lines := make([]string, 0, strings.Count(code, "\n")+1)
+	var line strings.Builder
+
+	for _, token := range lexer.Tokenize(code) {
+		for i, part := range strings.Split(token.Text, "\n") {
+			if i > 0 {
+				lines = append(lines, line.String())
+				line.Reset()
+			}
+			if len(part) == 0 {
+				continue
+			}
+
+			if token.Class == TokenText {
+				line.WriteString(escapeCode(part))
+			} else {
+				line.WriteString("<span class=\"hl-" + escapeAttribute(string(token.Class)) + "\">" + escapeCode(part) + "</span>")
+			}
+		}
+	}
+	return append(lines, line.String())
+}
+
+// Theme maps token classes to the CSS declarations they are styled with, such as "color: #d73a49".
+type Theme map[TokenClass]string
+
+// CSS returns a stylesheet with a rule for each class in the theme.
+func (t Theme) CSS() string {
+	classes := make([]TokenClass, 0, len(t))
+	for class := range t {
+		if class != TokenText {
+			classes = append(classes, class)
+		}
+	}
+	slices.Sort(classes)
+
+	var sb strings.Builder
+	for _, class := range classes {
+		sb.WriteString(".hl-" + string(class) + " { " + t[class] + " }\n")
+	}
+	return sb.String()
+}
+
+// LightTheme styles highlighted code for light backgrounds.
+var LightTheme = Theme{
+	TokenKeyword:     "color: #d73a49",
+	TokenType:        "color: #6f42c1",
+	TokenBuiltin:     "color: #005cc5",
+	TokenLiteral:     "color: #005cc5",
+	TokenString:      "color: #032f62",
+	TokenNumber:      "color: #005cc5",
+	TokenComment:     "color: #6a737d; font-style: italic",
+	TokenOperator:    "color: #d73a49",
+	TokenPunctuation: "color: #24292e",
+	TokenProperty:    "color: #005cc5",
+	TokenVariable:    "color: #e36209",
+	TokenTag:         "color: #22863a",
+	TokenAttribute:   "color: #6f42c1",
+}
+
+// DarkTheme styles highlighted code for dark backgrounds.
+var DarkTheme = Theme{
+	TokenKeyword:     "color: #ff7b72",
+	TokenType:        "color: #d2a8ff",
+	TokenBuiltin:     "color: #79c0ff",
+	TokenLiteral:     "color: #79c0ff",
+	TokenString:      "color: #a5d6ff",
+	TokenNumber:      "color: #79c0ff",
+	TokenComment:     "color: #8b949e; font-style: italic",
+	TokenOperator:    "color: #ff7b72",
+	TokenPunctuation: "color: #c9d1d9",
+	TokenProperty:    "color: #79c0ff",
+	TokenVariable:    "color: #ffa657",
+	TokenTag:         "color: #7ee787",
+	TokenAttribute:   "color: #d2a8ff",
+}
+
+// A pattern matched at the current position of a ruleLexer.
+type lexerRule struct {
+	pattern *regexp.Regexp
+	// classes of the pattern's capture groups, which must cover the whole match, or of the whole match if there is one
+	classes []TokenClass
+	// picks the class of the whole match instead, such as to tell keywords apart from other words
+	classify func(text string) TokenClass
+	// only match at the start of a line
+	lineStart bool
+	// state to switch to after matching, or "" to stay in the current state
+	next string
+}
+
+// A CodeLexer built from rules for each state, starting in the "root" state. At each position the first rule of the
+// current state that matches is used, and a character no rule matches is written as plain text.
+type ruleLexer map[string][]lexerRule
+
+func (l ruleLexer) Tokenize(code string) []CodeToken {
+	tokens := make([]CodeToken, 0)
+	// offset in code of the last token, whose text is sliced again when merging so long runs aren't copied
+	lastStart := 0
+	add := func(class TokenClass, start, end int) {
+		if start == end {
+			return
+		}
+		if last := len(tokens) - 1; last >= 0 && tokens[last].Class == class {
+			tokens[last].Text = code[lastStart:end]
+			return
+		}
+		tokens = append(tokens, CodeToken{Class: class, Text: code[start:end]})
+		lastStart = start
+	}
+
+	state := "root"
+	for pos := 0; pos < len(code); {
+		matched := false
+		for _, rule := range l[state] {
+			if rule.lineStart && pos > 0 && code[pos-1] != '\n' {
+				continue
+			}
+
+			match := rule.pattern.FindStringSubmatchIndex(code[pos:])
+			if match == nil || match[1] == 0 {
+				continue
+			}
+
+			end := pos + match[1]
+			switch {
+			case rule.classify != nil:
+				add(rule.classify(code[pos:end]), pos, end)
+			case len(rule.classes) == 0:
+				add(TokenText, pos, end)
+			case len(rule.classes) == 1:
+				add(rule.classes[0], pos, end)
+			default:
+				for i, class := range rule.classes {
+					if start := match[2*i+2]; start >= 0 {
+						add(class, pos+start, pos+match[2*i+3])
+					}
+				}
+			}
+
+			pos = end
+			if len(rule.next) > 0 {
+				state = rule.next
+			}
+			matched = true
+			break
+		}
+
+		if !matched {
+			// a rune at a time, so multi-byte characters aren't split
+			_, width := utf8.DecodeRuneInString(code[pos:])
+			add(TokenText, pos, pos+width)
+			pos += width
+		}
+	}
+	return tokens
+}
+
+// Returns a rule matching pattern at the current position, with the whole match or each capture group in turn
+// given the classes.
+func rule(pattern string, classes ...TokenClass) lexerRule {
+	return lexerRule{pattern: regexp.MustCompile(`^(?:` + pattern + `)`), classes: classes}
+}
+
+// Returns a rule matching pattern, classed by looking the match up in each of words in turn and using class for
+// anything not found.
+func wordRule(pattern string, class TokenClass, words ...map[string]TokenClass) lexerRule {
+	r := rule(pattern)
+	r.classify = func(text string) TokenClass {
+		for _, w := range words {
+			if wordClass, ok := w[text]; ok {
+				return wordClass
+			}
+		}
+		return class
+	}
+	return r
+}
+
+// Returns a wordRule that looks up matches in lower case, for languages such as SQL that ignore case.
+func foldedWordRule(pattern string, class TokenClass, words ...map[string]TokenClass) lexerRule {
+	r := wordRule(pattern, class, words...)
+	classify := r.classify
+	r.classify = func(text string) TokenClass {
+		return classify(strings.ToLower(text))
+	}
+	return r
+}
+
+// Returns each of the space separated words in list mapped to class.
+func words(class TokenClass, list string) map[string]TokenClass {
+	classes := make(map[string]TokenClass)
+	for _, word := range strings.Fields(list) {
+		classes[word] = class
+	}
+	return classes
+}
@@ -0,0 +1,258 @@
+package mdx
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Patterns shared by several languages. Strings and comments that are never closed run to the end of the line or the
+// code, so that the rest of the code isn't rescanned looking for their end.
+const (
+	whitespacePattern     = `\s+`
+	lineCommentPattern    = `//[^\n]*`
+	blockCommentPattern   = `/\*[\s\S]*?(?:\*/|$)`
+	doubleQuotedPattern   = `"(?:[^"\\\n]|\\.)*"?`
+	singleQuotedPattern   = `'(?:[^'\\\n]|\\.)*'?`
+	backtickStringPattern = "`(?:[^`\\\\]|\\\\[\\s\\S])*(?:`|$)"
+	cNumberPattern        = `0[xX][0-9a-fA-F_]+n?|0[bB][01_]+n?|0[oO][0-7_]+n?|(?:\d[\d_]*\.?[\d_]*|\.\d[\d_]*)(?:[eE][+-]?\d+)?[in]?`
+	identifierPattern     = `[\p{L}_][\p{L}\p{N}_]*`
+)
+
+// Returns the lexers registered by default, keyed by language name.
+func defaultLexers() map[string]CodeLexer {
+	lexers := make(map[string]CodeLexer)
+	add := func(lexer CodeLexer, names ...string) {
+		for _, name := range names {
+			lexers[name] = lexer
+		}
+	}
+
+	add(goLexer(), "go", "golang")
+	add(jsonLexer(), "json")
+	add(yamlLexer(), "yaml", "yml")
+	add(shellLexer(), "sh", "shell", "bash", "zsh")
+	add(htmlLexer(), "html", "xml", "svg")
+	add(cssLexer(), "css")
+	add(jsLexer(false), "js", "javascript", "jsx", "mjs")
+	add(jsLexer(true), "ts", "typescript", "tsx")
+	add(sqlLexer(), "sql")
+	return lexers
+}
+
+// Returns r changed to switch the lexer to state after matching.
+func (r lexerRule) then(state string) lexerRule {
+	r.next = state
+	return r
+}
+
+// Returns r changed to only match at the start of a line.
+func (r lexerRule) atLineStart() lexerRule {
+	r.lineStart = true
+	return r
+}
+
+func goLexer() CodeLexer {
+	keywords := words(TokenKeyword, "break case chan const continue default defer else fallthrough for func go goto if "+
+		"import interface map package range return select struct switch type var")
+	types := words(TokenType, "any bool byte comparable complex64 complex128 error float32 float64 int int8 int16 "+
+		"int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr")
+	literals := words(TokenLiteral, "true false nil iota")
+	builtins := words(TokenBuiltin, "append cap clear close complex copy delete imag len make max min new panic "+
+		"print println real recover")
+
+	return ruleLexer{"root": {
+		rule(whitespacePattern),
+		rule(lineCommentPattern+`|`+blockCommentPattern, TokenComment),
+		rule("`[^`]*(?:`|$)", TokenString),
+		rule(doubleQuotedPattern+`|`+singleQuotedPattern, TokenString),
+		rule(cNumberPattern, TokenNumber),
+		wordRule(identifierPattern, TokenText, keywords, types, literals, builtins),
+		rule(`<<=|>>=|&\^=|\.\.\.|&&|\|\||<-|\+\+|--|&\^|<<|>>|[-+*/%&|^<>=!:~]=?`, TokenOperator),
+		rule(`[()\[\]{},;.]`, TokenPunctuation),
+	}}
+}
+
+func jsLexer(typescript bool) CodeLexer {
+	keywordList := "as async await break case catch class const continue debugger default delete do else export " +
+		"extends finally for from function get if import in instanceof let new of return set static super switch " +
+		"this throw try typeof var void while with yield"
+	if typescript {
+		keywordList += " abstract declare enum implements interface keyof namespace private protected public readonly " +
+			"satisfies type"
+	}
+	keywords := words(TokenKeyword, keywordList)
+	types := words(TokenType, "any boolean never number object string symbol unknown bigint")
+	literals := words(TokenLiteral, "true false null undefined NaN Infinity")
+	builtins := words(TokenBuiltin, "Array Boolean console Date document Error JSON Map Math Number Object Promise "+
+		"RegExp Set String Symbol window")
+
+	if !typescript {
+		types = nil
+	}
+
+	return ruleLexer{"root": {
+		rule(whitespacePattern),
+		rule(lineCommentPattern+`|`+blockCommentPattern, TokenComment),
+		rule(backtickStringPattern+`|`+doubleQuotedPattern+`|`+singleQuotedPattern, TokenString),
+		rule(cNumberPattern, TokenNumber),
+		wordRule(`[\p{L}_$][\p{L}\p{N}_$]*`, TokenText, keywords, types, literals, builtins),
+		rule(`=>|\.\.\.|\?\?=?|\?\.|\*\*=?|&&=?|\|\|=?|>>>=?|<<=?|>>=?|===?|!==?|\+\+|--|[-+*/%&|^<>=!~?:]=?`, TokenOperator),
+		rule(`[()\[\]{},;.@#]`, TokenPunctuation),
+	}}
+}
+
+func jsonLexer() CodeLexer {
+	literals := words(TokenLiteral, "true false null")
+
+	return ruleLexer{"root": {
+		rule(whitespacePattern),
+		rule(`(`+doubleQuotedPattern+`)(\s*)(:)`, TokenProperty, TokenText, TokenPunctuation),
+		rule(doubleQuotedPattern, TokenString),
+		rule(`-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?`, TokenNumber),
+		wordRule(`[A-Za-z_]\w*`, TokenText, literals),
+		rule(`[{}\[\],:]`, TokenPunctuation),
+	}}
+}
+
+var yamlNumber = regexp.MustCompile(`^[-+]?(?:\d[\d_]*(?:\.\d*)?(?:[eE][-+]?\d+)?|0x[0-9a-fA-F]+|0o[0-7]+|\.inf|\.nan)$`)
+
+func yamlLexer() CodeLexer {
+	literals := words(TokenLiteral, "true false null yes no on off ~")
+	scalar := rule(`[^\s#:,\[\]{}"'&*!|>]+(?::[^\s,\[\]{}]+)*`)
+	scalar.classify = func(text string) TokenClass {
+		if class, ok := literals[strings.ToLower(text)]; ok {
+			return class
+		}
+		if yamlNumber.MatchString(strings.ToLower(text)) {
+			return TokenNumber
+		}
+		return TokenText
+	}
+
+	key := `[^\s#:"'\-][^\n:#]*?|-[^\s\n:#][^\n:#]*?|` + doubleQuotedPattern + `|` + singleQuotedPattern
+	return ruleLexer{"root": {
+		rule(`(---|\.\.\.)([ \t]*)`, TokenPunctuation, TokenText).atLineStart(),
+		rule(`([ \t]*)((?:-[ \t]+)*)(`+key+`)([ \t]*)(:)([ \t]|\n|$)`, TokenText, TokenPunctuation, TokenProperty, TokenText,
+			TokenPunctuation, TokenText).atLineStart(),
+		rule(`([ \t]*)(-)([ \t]|\n|$)`, TokenText, TokenPunctuation, TokenText).atLineStart(),
+		rule(whitespacePattern),
+		rule(`#[^\n]*`, TokenComment),
+		rule(doubleQuotedPattern+`|`+singleQuotedPattern, TokenString),
+		rule(`[&*][\w-]+`, TokenVariable),
+		rule(`!!?[\w-]*`, TokenType),
+		rule(`[|>][-+]?\d*`, TokenPunctuation),
+		scalar,
+		rule(`[:,\[\]{}?]`, TokenPunctuation),
+	}}
+}
+
+func shellLexer() CodeLexer {
+	keywords := words(TokenKeyword, "case do done elif else esac fi for function if in select then time until while")
+	builtins := words(TokenBuiltin, "alias bg break cd command continue echo eval exec exit export fg jobs kill let "+
+		"local printf pwd read readonly return set shift source test trap type ulimit umask unalias unset wait")
+
+	word := rule(`[\w./~%+,@:=-]+`)
+	word.classify = func(text string) TokenClass {
+		if class, ok := keywords[text]; ok {
+			return class
+		}
+		if class, ok := builtins[text]; ok {
+			return class
+		}
+		if len(strings.Trim(text, "0123456789")) == 0 {
+			return TokenNumber
+		}
+		return TokenText
+	}
+
+	return ruleLexer{"root": {
+		rule(whitespacePattern),
+		rule(`\$\{[^}\n]*\}?|\$[A-Za-z_]\w*|\$[0-9@#?$!*-]`, TokenVariable),
+		rule(`\$\(\(?`, TokenPunctuation),
+		rule(`#[^\n]*`, TokenComment),
+		rule(`'[^']*(?:'|$)`, TokenString),
+		rule(`"(?:[^"\\]|\\[\s\S])*(?:"|$)`, TokenString),
+		rule(`([A-Za-z_]\w*)(\+?=)`, TokenVariable, TokenOperator),
+		rule(`--?[A-Za-z][\w-]*`, TokenAttribute),
+		word,
+		rule(`&&|\|\||;;|[|&;<>]+|!`, TokenOperator),
+		rule(`[()\[\]{}]`, TokenPunctuation),
+	}}
+}
+
+func htmlLexer() CodeLexer {
+	return ruleLexer{
+		"root": {
+			rule(`<!--[\s\S]*?(?:-->|$)`, TokenComment),
+			rule(`<!\[CDATA\[[\s\S]*?(?:\]\]>|$)`, TokenString),
+			rule(`<[!?][A-Za-z][^>]*>?`, TokenKeyword),
+			rule(`(</?)([A-Za-z][\w:.-]*)`, TokenPunctuation, TokenTag).then("tag"),
+			rule(`&(?:#\d+|#[xX][0-9a-fA-F]+|\w+);`, TokenLiteral),
+			rule(`[^<&]+`),
+		},
+		"tag": {
+			rule(whitespacePattern),
+			rule(`/?>`, TokenPunctuation).then("root"),
+			rule(`"[^"]*(?:"|$)|'[^']*(?:'|$)`, TokenString),
+			rule(`(=)([^\s"'=<>`+"`"+`]+)`, TokenOperator, TokenString),
+			rule(`=`, TokenOperator),
+			rule(`[^\s=/>"']+`, TokenAttribute),
+		},
+	}
+}
+
+func cssLexer() CodeLexer {
+	comment := rule(blockCommentPattern, TokenComment)
+	str := rule(doubleQuotedPattern+`|`+singleQuotedPattern, TokenString)
+
+	return ruleLexer{
+		"root": {
+			rule(whitespacePattern),
+			comment,
+			str,
+			rule(`@[\w-]+`, TokenKeyword),
+			rule(`\{`, TokenPunctuation).then("block"),
+			rule(`[.#][\w-]+`, TokenAttribute),
+			rule(`::?[\w-]+`, TokenKeyword),
+			rule(`[A-Za-z][\w-]*|\*`, TokenTag),
+			rule(`[>+~,]`, TokenOperator),
+			rule(`[\[\]()=;]`, TokenPunctuation),
+		},
+		"block": {
+			rule(whitespacePattern),
+			comment,
+			str,
+			rule(`\}`, TokenPunctuation).then("root"),
+			rule(`(--[\w-]+|-?[A-Za-z][\w-]*)(\s*)(:)`, TokenProperty, TokenText, TokenPunctuation),
+			rule(`![Ii]mportant`, TokenKeyword),
+			rule(`#[0-9a-fA-F]{3,8}\b`, TokenLiteral),
+			rule(`[-+]?(?:\d+\.?\d*|\.\d+)(?:%|[A-Za-z]+)?`, TokenNumber),
+			rule(`[A-Za-z_-][\w-]*`),
+			rule(`[;:,(){}/*+]`, TokenPunctuation),
+		},
+	}
+}
+
+func sqlLexer() CodeLexer {
+	keywords := words(TokenKeyword, "add all alter and as asc begin between by cascade case check commit constraint "+
+		"create cross default delete desc distinct drop else end exists foreign from full group having if in index "+
+		"inner insert intersect into is join key left like limit not null offset on or order outer primary "+
+		"references replace returning right rollback select set table then transaction union unique update using "+
+		"values view when where with")
+	types := words(TokenType, "bigint blob bool boolean char date datetime decimal double float int integer json "+
+		"jsonb numeric real serial smallint text time timestamp timestamptz uuid varchar")
+	literals := words(TokenLiteral, "true false")
+	builtins := words(TokenBuiltin, "avg coalesce count lower max min now sum upper")
+
+	return ruleLexer{"root": {
+		rule(whitespacePattern),
+		rule(`--[^\n]*|`+blockCommentPattern, TokenComment),
+		rule(`'(?:[^']|'')*(?:'|$)`, TokenString),
+		rule(`"[^"\n]*"?|`+"`[^`\\n]*`?", TokenVariable),
+		rule(`::|<>|!=|<=|>=|\|\||[-+*/%=<>]`, TokenOperator),
+		rule(`\$\d+|[:@]\w+|\?`, TokenVariable),
+		rule(`\d+(?:\.\d+)?(?:[eE][-+]?\d+)?`, TokenNumber),
+		foldedWordRule(`[A-Za-z_][\w$]*`, TokenText, keywords, types, literals, builtins),
+		rule(`[(),;.\[\]]`, TokenPunctuation),
+	}}
+}
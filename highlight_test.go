@@ -0,0 +1,162 @@
+package mdx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Returns the tokens of code that aren't plain text, failing if the text of all the tokens isn't the code.
+func classedTokens(t *testing.T, language, code string) []CodeToken {
+	t.Helper()
+	lexer, ok := LookupLexer(language)
+	if !ok {
+		t.Fatalf("No lexer registered for %q", language)
+	}
+
+	var sb strings.Builder
+	classed := make([]CodeToken, 0)
+	for _, token := range lexer.Tokenize(code) {
+		sb.WriteString(token.Text)
+		if token.Class != TokenText {
+			classed = append(classed, token)
+		}
+	}
+	if sb.String() != code {
+		t.Errorf("%s tokens don't make up the code, expected=%q, got=%q", language, code, sb.String())
+	}
+	return classed
+}
+
+func TestHighlightLexers(t *testing.T) {
+	tests := []struct {
+		language string
+		code     string
+		expected []CodeToken
+	}{
+		{"go", "func f() string { return \"a<b\" } // c", []CodeToken{
+			{TokenKeyword, "func"}, {TokenPunctuation, "()"}, {TokenType, "string"}, {TokenPunctuation, "{"},
+			{TokenKeyword, "return"}, {TokenString, "\"a<b\""}, {TokenPunctuation, "}"}, {TokenComment, "// c"},
+		}},
+		{"json", `{"a": [1, true]}`, []CodeToken{
+			{TokenPunctuation, "{"}, {TokenProperty, "\"a\""}, {TokenPunctuation, ":"}, {TokenPunctuation, "["},
+			{TokenNumber, "1"}, {TokenPunctuation, ","}, {TokenLiteral, "true"}, {TokenPunctuation, "]}"},
+		}},
+		{"yaml", "# c\nname: app\nports:\n  - 80\n", []CodeToken{
+			{TokenComment, "# c"}, {TokenProperty, "name"}, {TokenPunctuation, ":"}, {TokenProperty, "ports"},
+			{TokenPunctuation, ":"}, {TokenPunctuation, "-"}, {TokenNumber, "80"},
+		}},
+		{"bash", "export A=1; echo \"$HOME\" | grep -v x", []CodeToken{
+			{TokenBuiltin, "export"}, {TokenVariable, "A"}, {TokenOperator, "="}, {TokenNumber, "1"},
+			{TokenOperator, ";"}, {TokenBuiltin, "echo"}, {TokenString, "\"$HOME\""}, {TokenOperator, "|"},
+			{TokenAttribute, "-v"},
+		}},
+		{"html", `<a href="/x">T &amp;</a>`, []CodeToken{
+			{TokenPunctuation, "<"}, {TokenTag, "a"}, {TokenAttribute, "href"}, {TokenOperator, "="},
+			{TokenString, "\"/x\""}, {TokenPunctuation, ">"}, {TokenLiteral, "&amp;"}, {TokenPunctuation, "</"},
+			{TokenTag, "a"}, {TokenPunctuation, ">"},
+		}},
+		{"css", ".a > p { color: #fff; margin: 0 2px; }", []CodeToken{
+			{TokenAttribute, ".a"}, {TokenOperator, ">"}, {TokenTag, "p"}, {TokenPunctuation, "{"},
+			{TokenProperty, "color"}, {TokenPunctuation, ":"}, {TokenLiteral, "#fff"}, {TokenPunctuation, ";"},
+			{TokenProperty, "margin"}, {TokenPunctuation, ":"}, {TokenNumber, "0"}, {TokenNumber, "2px"},
+			{TokenPunctuation, ";"}, {TokenPunctuation, "}"},
+		}},
+		{"js", "const x = `a` ?? null;", []CodeToken{
+			{TokenKeyword, "const"}, {TokenOperator, "="}, {TokenString, "`a`"}, {TokenOperator, "??"},
+			{TokenLiteral, "null"}, {TokenPunctuation, ";"},
+		}},
+		{"ts", "let n: number = 1", []CodeToken{
+			{TokenKeyword, "let"}, {TokenOperator, ":"}, {TokenType, "number"}, {TokenOperator, "="},
+			{TokenNumber, "1"},
+		}},
+		{"SQL", "SELECT id FROM users WHERE name = 'x';", []CodeToken{
+			{TokenKeyword, "SELECT"}, {TokenKeyword, "FROM"}, {TokenKeyword, "WHERE"}, {TokenOperator, "="},
+			{TokenString, "'x'"}, {TokenPunctuation, ";"},
+		}},
+	}
+
+	for _, test := range tests {
+		if actual := classedTokens(t, test.language, test.code); !slices.Equal(actual, test.expected) {
+			t.Errorf("%s tokens wrong, expected=%v, got=%v", test.language, test.expected, actual)
+		}
+	}
+}
+
+func TestHighlightLexersKeepOddInput(t *testing.T) {
+	inputs := []string{"", "\"unclosed", "/* unclosed\n", "`", "<a href=", "{ color: ", "'it''s", "\xff\xfe", "ü → ∞", "\n\n"}
+	lexerRegistry.RLock()
+	languages := make([]string, 0, len(lexerRegistry.lexers))
+	for language := range lexerRegistry.lexers {
+		languages = append(languages, language)
+	}
+	lexerRegistry.RUnlock()
+
+	for _, language := range languages {
+		for _, input := range inputs {
+			classedTokens(t, language, input)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	highlighted, ok := Highlight("x := 1 // <a>\n/* b\nc */", "go")
+	expected := "x <span class=\"hl-operator\">:=</span> <span class=\"hl-number\">1</span> <span class=\"hl-comment\">// &lt;a&gt;</span>\n" +
+		"<span class=\"hl-comment\">/* b</span>\n<span class=\"hl-comment\">c */</span>"
+	if !ok || highlighted != expected {
+		t.Errorf("Highlight wrong, expected=%q, got=%q", expected, highlighted)
+	}
+
+	if _, ok := Highlight("x", "brainfuck"); ok {
+		t.Errorf("Expected no lexer for unknown language")
+	}
+}
+
+func TestRegisterLexer(t *testing.T) {
+	lexer := CodeLexerFunc(func(code string) []CodeToken {
+		return []CodeToken{{Class: TokenKeyword, Text: code}}
+	})
+	RegisterLexer(lexer, "Shout")
+	defer func() {
+		lexerRegistry.Lock()
+		delete(lexerRegistry.lexers, "shout")
+		lexerRegistry.Unlock()
+	}()
+
+	if _, ok := LookupLexer("SHOUT"); !ok {
+		t.Fatalf("Registered lexer not found")
+	}
+
+	codeBlock := &CodeBlock{Language: "shout", Content: "HEY"}
+	expected := "<div class=\"code-block\">\n    <pre><span class=\"hl-keyword\">HEY</span></pre>\n</div>"
+	if actual := codeBlock.Raw(); actual != expected {
+		t.Errorf("CodeBlock with registered lexer wrong, expected=%q, got=%q", expected, actual)
+	}
+}
+
+func TestThemeCSS(t *testing.T) {
+	theme := Theme{TokenString: "color: green", TokenComment: "color: grey; font-style: italic", TokenText: "color: red"}
+	expected := ".hl-comment { color: grey; font-style: italic }\n.hl-string { color: green }\n"
+	if actual := theme.CSS(); actual != expected {
+		t.Errorf("Theme CSS wrong, expected=%q, got=%q", expected, actual)
+	}
+
+	for _, theme := range []Theme{LightTheme, DarkTheme} {
+		if strings.Count(theme.CSS(), "\n") != 13 {
+			t.Errorf("Expected a rule for every token class, got=%q", theme.CSS())
+		}
+	}
+}
+
+func FuzzHighlight(f *testing.F) {
+	f.Add("go", "func main() {\n\t/* a */ s := `b` + \"c\\\"\"\n}")
+	f.Add("yaml", "a: &b\n  - !!str c # d\n---\ne: |\n  f")
+	f.Add("html", "<!-- a --><p class=b>&lt;c</p>")
+	f.Add("sql", "select 'a''b' from \"c\" where d = $1 -- e")
+
+	f.Fuzz(func(t *testing.T, language, code string) {
+		if _, ok := LookupLexer(language); ok {
+			classedTokens(t, language, code)
+		}
+	})
+}
@@ -0,0 +1,639 @@
+package mdx
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// HtmlRenderer is the default Renderer, producing indented HTML.
+// Text, attribute values, URLs and code are escaped so that they can't be mistaken for markup.
+type HtmlRenderer struct {
+	// Write text and code block content as is, for trusted documents that intentionally contain HTML.
+	// Attribute values and URLs are always escaped, and this is ignored in safe mode.
+	AllowRawHtml bool
+	// Render in safe mode for untrusted documents, restricting attributes, URLs and buttons. Nil renders everything.
+	Safe *SafeOptions
+	// Properties added to every element of a kind, merged with the element's own properties which take precedence.
+	// Kinds are named after the tag the element is rendered as, such as "h2", "p", "a", "img" or "li", except for
+	// "codeBlock" which is rendered as a div. Task list checkboxes are "input", table cells are "th" or "td", and
+	// fenced code blocks are "pre".
+	DefaultProperties map[string][]Property
+}
+
+func (r *HtmlRenderer) allowsRawHtml() bool {
+	return r.AllowRawHtml && r.Safe == nil
+}
+
+func (r *HtmlRenderer) RenderFragment(w *RenderWriter, node *Fragment) {
+	value := node.Value
+	if !r.allowsRawHtml() {
+		value = escapeText(value)
+	}
+
+	if w.Inline() {
+		w.WriteString(value)
+		return
+	}
+
+	w.WriteString(value + "\n")
+}
+
+func (r *HtmlRenderer) RenderLineBreak(w *RenderWriter, node *LineBreak) {
+	if w.Inline() {
+		w.WriteString("<br/>")
+		return
+	}
+
+	w.WriteString(indent(w) + "<br/>\n")
+}
+
+func (r *HtmlRenderer) RenderHeading(w *RenderWriter, node *Heading) {
+	openingTag := fmt.Sprintf("<h%d%s>", node.Level, r.propertyString(fmt.Sprintf("h%d", node.Level), node.Properties))
+	closingTag := fmt.Sprintf("</h%d>", node.Level)
+	writeContainer(w, openingTag, closingTag, node.Content, blockContainer)
+}
+
+func (r *HtmlRenderer) RenderParagraph(w *RenderWriter, node *Paragraph) {
+	openingTag := "<p" + r.propertyString("p", node.Properties) + ">"
+	writeContainer(w, openingTag, "</p>", node.Content, paragraphContainer)
+}
+
+func (r *HtmlRenderer) RenderCode(w *RenderWriter, node *Code) {
+	w.WriteString("<code" + r.propertyString("code", node.Properties) + ">" + escapeCode(node.Text) + "</code>")
+	if !w.Inline() {
+		w.WriteString("\n")
+	}
+}
+
+func (r *HtmlRenderer) RenderBold(w *RenderWriter, node *Bold) {
+	openingTag := "<strong" + r.propertyString("strong", node.Properties) + ">"
+	writeContainer(w, openingTag, "</strong>", node.Content, inlineContainer)
+}
+
+func (r *HtmlRenderer) RenderItalic(w *RenderWriter, node *Italic) {
+	openingTag := "<em" + r.propertyString("em", node.Properties) + ">"
+	writeContainer(w, openingTag, "</em>", node.Content, inlineContainer)
+}
+
+func (r *HtmlRenderer) RenderBlockQuote(w *RenderWriter, node *BlockQuote) {
+	openingTag := "<blockquote" + r.propertyString("blockquote", node.Properties) + ">"
+	writeContainer(w, openingTag, "</blockquote>", node.Content, blockContainer)
+}
+
+func (r *HtmlRenderer) RenderListItem(w *RenderWriter, node *ListItem) {
+	properties := node.Properties
+	var checkbox string
+	if node.Task {
+		properties = append([]Property{{Name: "class", Value: "task-list-item"}}, properties...)
+		checkbox = r.checkboxString(node)
+	}
+
+	if w.Inline() {
+		w.WriteString("<li" + r.propertyString("li", properties) + ">" + checkbox)
+		w.RenderInline(node.Component)
+		for _, child := range node.Children {
+			w.RenderInline(child)
+		}
+		w.WriteString("</li>")
+		return
+	}
+
+	openingTag := "<li" + r.propertyString("li", properties) + ">\n"
+	closingTag := "</li>"
+	indentLevel := w.IndentLevel()
+	indentPrefix := indent(w)
+
+	w.WriteString(indentPrefix + openingTag)
+	if node.Task {
+		w.WriteString(indentPrefix + INDENT + checkbox + "\n")
+	}
+
+	for _, child := range slices.Concat([]Node{node.Component}, node.Children) {
+		if child.Type() == Inline {
+			w.WriteString(strings.Repeat(INDENT, indentLevel+1))
+		}
+		w.Render(child, indentLevel+1)
+	}
+
+	w.WriteString(indentPrefix + closingTag + "\n")
+}
+
+// Returns the <input> for a task list item. Checkboxes are disabled unless given the enabled property, either on the
+// item or through the "input" default properties.
+func (r *HtmlRenderer) checkboxString(node *ListItem) string {
+	properties, _ := MergeProperties(slices.Concat(r.DefaultProperties["input"], node.CheckboxProperties))
+
+	enabled := false
+	attributes := make([]Property, 0, len(properties))
+	for _, property := range properties {
+		if strings.EqualFold(property.Name, "enabled") {
+			enabled = true
+			continue
+		}
+		attributes = append(attributes, property)
+	}
+
+	checkbox := "<input type=\"checkbox\""
+	if !enabled {
+		checkbox += " disabled"
+	}
+	if node.Checked {
+		checkbox += " checked"
+	}
+	return checkbox + r.attributeString(attributes) + "/>"
+}
+
+func (r *HtmlRenderer) RenderOrderedList(w *RenderWriter, node *OrderedList) {
+	if w.Inline() {
+		w.WriteString(fmt.Sprintf("<ol start=\"%d\"%s>\n", node.Start, r.propertyString("ol", node.Properties)))
+		writeRawListItems(w, node.ListItems)
+		w.WriteString("</ol>")
+		return
+	}
+
+	writeList(w, "<ol"+r.propertyString("ol", node.Properties)+">", "</ol>", node.ListItems)
+}
+
+func (r *HtmlRenderer) RenderUnorderedList(w *RenderWriter, node *UnorderedList) {
+	if w.Inline() {
+		w.WriteString("<ul" + r.propertyString("ul", node.Properties) + ">\n")
+		writeRawListItems(w, node.ListItems)
+		w.WriteString("</ul>")
+		return
+	}
+
+	writeList(w, "<ul"+r.propertyString("ul", node.Properties)+">", "</ul>", node.ListItems)
+}
+
+func (r *HtmlRenderer) RenderImage(w *RenderWriter, node *Image) {
+	if r.Safe != nil && !r.Safe.allowsUrl(node.ImgUrl) {
+		return
+	}
+
+	imgUrl, altText := escapeUrl(node.ImgUrl), escapeAttribute(node.AltText)
+	tag := fmt.Sprintf("<img%s src=\"%s\" alt=\"%s\"/>", r.propertyString("img", node.Properties), imgUrl, altText)
+	writeVoidElement(w, tag)
+}
+
+func (r *HtmlRenderer) RenderHorizontalRule(w *RenderWriter, node *HorizontalRule) {
+	writeVoidElement(w, "<hr"+r.propertyString("hr", node.Properties)+"/>")
+}
+
+func (r *HtmlRenderer) RenderLink(w *RenderWriter, node *Link) {
+	url := node.Url
+	if r.Safe != nil && !r.Safe.allowsUrl(url) {
+		url = "#"
+	}
+
+	openingTag := fmt.Sprintf("<a%s href=\"%s\" target=_blank>", r.propertyString("a", node.Properties), escapeUrl(url))
+	writeContainer(w, openingTag, "</a>", node.Content, inlineContainer)
+}
+
+func (r *HtmlRenderer) RenderButton(w *RenderWriter, node *Button) {
+	if r.Safe != nil && r.Safe.DisableButtons {
+		return
+	}
+
+	onClick := fmt.Sprintf(" onclick=\"%s(this)\"", escapeAttribute(node.OnClick))
+	if r.Safe != nil && !r.Safe.allowsHandler(node.OnClick) {
+		onClick = ""
+	}
+
+	openingTag := "<button" + r.propertyString("button", node.Properties) + onClick + ">"
+	if w.Inline() {
+		w.WriteString(openingTag + "\n" + INDENT)
+		w.RenderInline(node.Content...)
+		w.WriteString("\n</button>")
+		return
+	}
+
+	writeContainer(w, openingTag, "</button>", node.Content, blockContainer)
+}
+
+func (r *HtmlRenderer) RenderDiv(w *RenderWriter, node *Div) {
+	writeChildren(w, "div", r.propertyString("div", node.Properties), node.Children)
+}
+
+func (r *HtmlRenderer) RenderNav(w *RenderWriter, node *Nav) {
+	writeChildren(w, "nav", r.propertyString("nav", node.Properties), node.Children)
+}
+
+func (r *HtmlRenderer) RenderSpan(w *RenderWriter, node *Span) {
+	if w.Inline() && len(node.Content) == 0 {
+		w.WriteString("<span" + r.propertyString("span", node.Properties) + "/>")
+		return
+	}
+
+	openingTag := "<span" + r.propertyString("span", node.Properties) + ">"
+	writeContainer(w, openingTag, "</span>", node.Content, inlineContainer)
+}
+
+func (r *HtmlRenderer) RenderCodeBlock(w *RenderWriter, node *CodeBlock) {
+	properties := slices.Concat([]Property{{Name: "class", Value: "code-block"}}, node.Properties)
+	openingTag := "<div" + r.propertyString("codeBlock", properties) + ">"
+	closingTag := "</div>"
+	lines := r.codeLines(node)
+
+	if w.Inline() {
+		w.WriteString(openingTag + "\n")
+		for _, line := range lines {
+			w.WriteString(INDENT + line + "\n")
+		}
+		w.WriteString(closingTag)
+		return
+	}
+
+	indentPrefix := indent(w)
+	w.WriteString(indentPrefix + openingTag + "\n")
+	for _, line := range lines {
+		w.WriteString(indentPrefix + INDENT + line + "\n")
+	}
+	w.WriteString(indentPrefix + closingTag + "\n")
+}
+
+func (r *HtmlRenderer) RenderFencedCodeBlock(w *RenderWriter, node *FencedCodeBlock) {
+	content := node.Content
+	if !r.allowsRawHtml() {
+		content = escapeCode(content)
+	}
+
+	codeTag := "<code>"
+	if len(node.Language) > 0 {
+		codeTag = "<code class=\"language-" + escapeAttribute(node.Language) + "\">"
+	}
+	html := "<pre" + r.propertyString("pre", node.Properties) + ">" + codeTag + content + "</code></pre>"
+
+	if w.Inline() {
+		w.WriteString(html)
+		return
+	}
+	w.WriteString(indent(w) + html + "\n")
+}
+
+// Returns the elements inside a code block: its title, then a <pre> for each line of code. Lines are highlighted when
+// a lexer is registered for the block's language, and highlighted code is always escaped, even when raw HTML is
+// allowed.
+func (r *HtmlRenderer) codeLines(node *CodeBlock) []string {
+	lines := strings.Split(node.Content, "\n")
+	highlighted := false
+	if len(node.Language) > 0 {
+		if lexer, ok := LookupLexer(node.Language); ok {
+			lines = highlightLines(strings.Join(lines, "\n"), lexer)
+			highlighted = true
+		}
+	}
+
+	elements := make([]string, 0, len(lines)+1)
+	if len(node.Title) > 0 {
+		elements = append(elements, "<div class=\"code-block-title\">"+escapeText(node.Title)+"</div>")
+	}
+
+	firstLine := max(node.StartLine, 1)
+	for i, line := range lines {
+		if !highlighted && !r.allowsRawHtml() {
+			line = escapeCode(line)
+		}
+
+		number := firstLine + i
+		if node.LineNumbers {
+			line = "<span class=\"line-number\">" + strconv.Itoa(number) + "</span>" + line
+		}
+
+		if inLineRanges(node.HighlightLines, number) {
+			elements = append(elements, "<pre class=\"highlighted\">"+line+"</pre>")
+		} else {
+			elements = append(elements, "<pre>"+line+"</pre>")
+		}
+	}
+	return elements
+}
+
+func renderBody(w *RenderWriter, node *body) {
+	if w.Inline() {
+		w.WriteString("<body>\n")
+		for _, child := range node.Children {
+			w.WriteString(INDENT)
+			w.RenderInline(child)
+			w.WriteString("\n")
+		}
+		w.WriteString("</body>")
+		return
+	}
+
+	indentLevel := w.IndentLevel()
+	indentPrefix := indent(w)
+
+	w.WriteString(indentPrefix + "<body>\n")
+	for _, child := range node.Children {
+		if child.Type() == Inline {
+			w.WriteString(strings.Repeat(INDENT, indentLevel+1))
+		}
+		w.Render(child, indentLevel+1)
+	}
+	w.WriteString(indentPrefix + "</body>")
+}
+
+type containerStyle int
+
+const (
+	// opening tag starts a new line, closing tag always on its own line when content has block elements
+	blockContainer containerStyle = iota
+	// opening tag continues the current line, closing tag indented when content has block elements
+	paragraphContainer
+	// opening tag continues the current line, closing tag follows content directly
+	inlineContainer
+)
+
+// Writes content wrapped in the given tags. Inline content is joined onto as few lines as fit within MAX_LENGTH,
+// and any block content is placed on its own lines, indented one level deeper.
+func writeContainer(w *RenderWriter, openingTag, closingTag string, content []Node, style containerStyle) {
+	if w.Inline() {
+		w.WriteString(openingTag)
+		w.RenderInline(content...)
+		w.WriteString(closingTag)
+		return
+	}
+
+	indentLevel := w.IndentLevel()
+	indentPrefix := indent(w)
+
+	if style == blockContainer {
+		w.WriteString(indentPrefix)
+	}
+	w.WriteString(openingTag)
+
+	containsBlockElement := slices.ContainsFunc(content, func(c Node) bool {
+		return c.Type() == Block
+	})
+
+	if containsBlockElement {
+		// put child component on new line and indented + 1
+		w.WriteString("\n")
+
+		var inline strings.Builder
+		flushInline := func() {
+			// split the inline string onto lines of its own
+			lineLength := len(indentPrefix) + len(openingTag) + inline.Len() + len(closingTag)
+			if lineLength < MAX_LENGTH {
+				w.WriteString(inline.String() + "\n")
+			} else {
+				writeWrappedLines(w, inline.String(), indentPrefix+INDENT)
+			}
+			inline.Reset()
+		}
+
+		for _, child := range content {
+			if child.Type() == Inline {
+				inline.WriteString(indentPrefix + INDENT + w.InlineString(child))
+			} else {
+				if inline.Len() > 0 {
+					flushInline()
+				}
+				w.Render(child, indentLevel+1)
+			}
+		}
+
+		if inline.Len() > 0 {
+			flushInline()
+		}
+
+		if style != inlineContainer {
+			w.WriteString(indentPrefix)
+		}
+	} else {
+		// check if everything can fit on one line. if not, figure it out
+		inlineString := w.InlineString(content...)
+		lineLength := len(indentPrefix) + len(openingTag) + len(inlineString) + len(closingTag)
+		writeInlineString(w, inlineString, indentPrefix, lineLength)
+	}
+
+	w.WriteString(closingTag + "\n")
+}
+
+func writeList(w *RenderWriter, openingTag, closingTag string, items []ListItem) {
+	indentLevel := w.IndentLevel()
+	indentPrefix := indent(w)
+
+	w.WriteString(indentPrefix + openingTag + "\n")
+	for i := range items {
+		w.Render(&items[i], indentLevel+1)
+	}
+	w.WriteString(indentPrefix + closingTag + "\n")
+}
+
+func writeRawListItems(w *RenderWriter, items []ListItem) {
+	for i := range items {
+		w.WriteString(INDENT)
+		w.RenderInline(&items[i])
+		w.WriteString("\n")
+	}
+}
+
+// Writes children of a div-like element, each formatted on their own line.
+func writeChildren(w *RenderWriter, tag string, attributes string, children []Node) {
+	openingTag := "<" + tag + attributes + ">"
+	closingTag := "</" + tag + ">"
+
+	if w.Inline() {
+		if len(children) == 0 {
+			w.WriteString("<" + tag + attributes + "/>")
+			return
+		}
+
+		w.WriteString(openingTag + "\n")
+		for _, child := range children {
+			w.WriteString(INDENT)
+			w.RenderInline(child)
+			w.WriteString("\n")
+		}
+		w.WriteString(closingTag)
+		return
+	}
+
+	indentLevel := w.IndentLevel()
+	indentPrefix := indent(w)
+
+	w.WriteString(indentPrefix + openingTag + "\n")
+	for _, child := range children {
+		if child.Type() == Inline {
+			w.WriteString(strings.Repeat(INDENT, indentLevel+1))
+		}
+		w.Render(child, indentLevel+1)
+	}
+	w.WriteString(indentPrefix + closingTag + "\n")
+}
+
+// Writes a self closing element on its own line.
+func writeVoidElement(w *RenderWriter, tag string) {
+	if w.Inline() {
+		w.WriteString(tag)
+		return
+	}
+
+	w.WriteString(indent(w) + tag + "\n")
+}
+
+func (r *HtmlRenderer) RenderTable(w *RenderWriter, node *Table) {
+	openingTag := "<table" + r.propertyString("table", node.Properties) + ">"
+	if w.Inline() {
+		w.WriteString(openingTag + "<thead>")
+		w.RenderInline(&node.Header)
+		w.WriteString("</thead>")
+		if len(node.Rows) > 0 {
+			w.WriteString("<tbody>")
+			for i := range node.Rows {
+				w.RenderInline(&node.Rows[i])
+			}
+			w.WriteString("</tbody>")
+		}
+		w.WriteString("</table>")
+		return
+	}
+
+	indentLevel := w.IndentLevel()
+	indentPrefix := indent(w)
+
+	w.WriteString(indentPrefix + openingTag + "\n")
+	w.WriteString(indentPrefix + INDENT + "<thead>\n")
+	w.Render(&node.Header, indentLevel+2)
+	w.WriteString(indentPrefix + INDENT + "</thead>\n")
+	if len(node.Rows) > 0 {
+		w.WriteString(indentPrefix + INDENT + "<tbody>\n")
+		for i := range node.Rows {
+			w.Render(&node.Rows[i], indentLevel+2)
+		}
+		w.WriteString(indentPrefix + INDENT + "</tbody>\n")
+	}
+	w.WriteString(indentPrefix + "</table>\n")
+}
+
+func (r *HtmlRenderer) RenderTableRow(w *RenderWriter, node *TableRow) {
+	openingTag := "<tr" + r.propertyString("tr", node.Properties) + ">"
+	if w.Inline() {
+		w.WriteString(openingTag)
+		for i := range node.Cells {
+			w.RenderInline(&node.Cells[i])
+		}
+		w.WriteString("</tr>")
+		return
+	}
+
+	indentLevel := w.IndentLevel()
+	indentPrefix := indent(w)
+
+	w.WriteString(indentPrefix + openingTag + "\n")
+	for i := range node.Cells {
+		w.Render(&node.Cells[i], indentLevel+1)
+	}
+	w.WriteString(indentPrefix + "</tr>\n")
+}
+
+func (r *HtmlRenderer) RenderTableCell(w *RenderWriter, node *TableCell) {
+	tag := "td"
+	if node.Header {
+		tag = "th"
+	}
+
+	openingTag := "<" + tag + alignAttribute(node.Align) + r.propertyString(tag, node.Properties) + ">"
+	writeContainer(w, openingTag, "</"+tag+">", node.Content, blockContainer)
+}
+
+// Returns the align attribute for a table cell, preceded by a space, or nothing for AlignNone.
+func alignAttribute(align Alignment) string {
+	switch align {
+	case AlignLeft:
+		return " align=\"left\""
+	case AlignCenter:
+		return " align=\"center\""
+	case AlignRight:
+		return " align=\"right\""
+	}
+	return ""
+}
+
+func indent(w *RenderWriter) string {
+	return strings.Repeat(INDENT, w.IndentLevel())
+}
+
+// Returns the default properties for kind merged with properties as HTML attributes, each preceded by a space.
+// Properties with names that can't be written as an attribute, or that safe mode doesn't allow, are left out.
+func (r *HtmlRenderer) propertyString(kind string, properties []Property) string {
+	properties, _ = MergeProperties(slices.Concat(r.DefaultProperties[kind], properties))
+	return r.attributeString(properties)
+}
+
+// Returns properties as HTML attributes without merging them with any defaults.
+func (r *HtmlRenderer) attributeString(properties []Property) string {
+	var sb strings.Builder
+	for _, property := range properties {
+		if !isValidAttributeName(property.Name) {
+			continue
+		}
+
+		value := escapeAttribute(property.Value)
+		if r.Safe != nil {
+			if !r.Safe.allowsAttribute(property.Name) {
+				continue
+			}
+
+			if isUrlAttribute(property.Name) {
+				if !r.Safe.allowsUrl(property.Value) {
+					continue
+				}
+				// character references could otherwise spell out a blocked scheme
+				value = escapeUrl(property.Value)
+			}
+		}
+
+		if len(property.Value) == 0 {
+			// boolean attribute such as hidden
+			sb.WriteString(" " + property.Name)
+			continue
+		}
+		sb.WriteString(" " + property.Name + "=\"" + value + "\"")
+	}
+	return sb.String()
+}
+
+// Writes inlineString to w, wrapping it onto multiple lines indented from indentPrefix when lineLength
+// exceeds MAX_LENGTH.
+func writeInlineString(w *RenderWriter, inlineString string, indentPrefix string, lineLength int) {
+	if lineLength < MAX_LENGTH {
+		w.WriteString(inlineString)
+		return
+	}
+
+	w.WriteString("\n")
+	writeWrappedLines(w, inlineString, indentPrefix+INDENT)
+	w.WriteString(indentPrefix)
+}
+
+// Writes inlineString to w as lines no longer than MAX_LENGTH where possible, each starting with indentString and
+// ending with a line break.
+func writeWrappedLines(w *RenderWriter, inlineString string, indentString string) {
+	words := strings.Split(inlineString, " ")
+	currentLine := words[0]
+	words = words[1:]
+
+	for len(words) > 0 {
+		canAdd := MAX_LENGTH - len(currentLine) - len(indentString)
+
+		// not sure if I prefer the look of len(currentLine+words[0]) or just len(words[0]) here
+		if len(words[0]) > canAdd {
+			w.WriteString(indentString + currentLine + "\n")
+			currentLine = ""
+		}
+
+		if len(currentLine) > 0 {
+			currentLine += " "
+		}
+		currentLine += words[0]
+		words = words[1:]
+	}
+
+	if len(currentLine) > 0 {
+		w.WriteString(indentString + currentLine + "\n")
+	}
+}
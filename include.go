@@ -0,0 +1,197 @@
+package mdx
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The file, and optionally the part of it, that a code block includes.
+type codeInclude struct {
+	// path relative to the document, as written in the file property
+	file string
+	// line number or range, as written in the range property
+	lines  string
+	region string
+}
+
+// Comment openings and closings that region markers can be written with, so that markers work in most languages.
+var (
+	commentOpenings = []string{"<!--", "//", "/*", "--", "#", ";"}
+	commentClosings = []string{"-->", "*/"}
+)
+
+// Reads the code a code block includes, resolving its file relative to the directory of documentFilename. Returns the
+// code with its lines separated by line breaks, tabs expanded and common indentation removed, along with the number of
+// its first line in the file.
+func readInclude(documentFilename string, include codeInclude) (string, int, error) {
+	path, pathErr := includePath(documentFilename, include.file)
+	if pathErr != nil {
+		return "", 0, pathErr
+	}
+
+	data, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return "", 0, fmt.Errorf("Included file %q can't be read: %v", include.file, readErr)
+	}
+
+	code := strings.ReplaceAll(string(data), "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+	firstLine := 1
+
+	if len(include.lines) > 0 && len(include.region) > 0 {
+		return "", 0, fmt.Errorf("Included file %q can be limited by range or by region, not both", include.file)
+	}
+
+	if len(include.lines) > 0 {
+		ranges, ok := parseLineRanges(include.lines)
+		if !ok || len(ranges) != 1 {
+			return "", 0, fmt.Errorf("Invalid range %q, expected a line number or range such as 10-20", include.lines)
+		}
+		if ranges[0].End > len(lines) {
+			return "", 0, fmt.Errorf("Range %s is outside %q, which has %d lines", include.lines, include.file, len(lines))
+		}
+		firstLine = ranges[0].Start
+		lines = lines[ranges[0].Start-1 : ranges[0].End]
+	}
+
+	if len(include.region) > 0 {
+		var regionErr error
+		if lines, firstLine, regionErr = regionLines(lines, include.region); regionErr != nil {
+			return "", 0, fmt.Errorf("%s in %q", regionErr.Error(), include.file)
+		}
+	}
+
+	for i := range lines {
+		lines[i] = strings.ReplaceAll(lines[i], "\t", "    ")
+	}
+	return strings.Join(dedent(lines), "\n"), firstLine, nil
+}
+
+// Returns the path of an included file with any symbolic links resolved. Files outside the directory of
+// documentFilename, whether through an absolute path, .. or a link, can't be included, so that a document can't read
+// whatever the process running it can.
+func includePath(documentFilename string, file string) (string, error) {
+	outsideErr := fmt.Errorf("Included file %q must be inside the document's directory", file)
+	path := filepath.FromSlash(file)
+	if !filepath.IsLocal(path) {
+		return "", outsideErr
+	}
+
+	dir, dirErr := filepath.Abs(filepath.Dir(documentFilename))
+	if dirErr == nil {
+		dir, dirErr = filepath.EvalSymlinks(dir)
+	}
+	if dirErr != nil {
+		return "", fmt.Errorf("Included file %q can't be read: %v", file, dirErr)
+	}
+
+	resolved, linkErr := filepath.EvalSymlinks(filepath.Join(dir, path))
+	if os.IsNotExist(linkErr) {
+		return "", fmt.Errorf("Included file %q not found", file)
+	} else if linkErr != nil {
+		return "", fmt.Errorf("Included file %q can't be read: %v", file, linkErr)
+	}
+
+	if relative, relErr := filepath.Rel(dir, resolved); relErr != nil || !filepath.IsLocal(relative) {
+		return "", outsideErr
+	}
+	return resolved, nil
+}
+
+// Returns the lines between the markers of the named region, such as "// region: setup" and "// endregion", without
+// the markers of any regions nested inside it, along with the number of its first line.
+func regionLines(lines []string, name string) ([]string, int, error) {
+	start := -1
+	for i, line := range lines {
+		if markerName, end, ok := regionMarker(line); ok && !end && markerName == name {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		return nil, 0, fmt.Errorf("Region %q not found", name)
+	}
+
+	region := make([]string, 0)
+	depth := 0
+	for _, line := range lines[start:] {
+		markerName, end, ok := regionMarker(line)
+		switch {
+		case !ok:
+			region = append(region, line)
+		case !end:
+			depth++
+		case markerName == name || (len(markerName) == 0 && depth == 0):
+			return region, start + 1, nil
+		case depth > 0:
+			depth--
+		}
+	}
+	return nil, 0, fmt.Errorf("Region %q has no endregion marker", name)
+}
+
+// Parses a line holding only a comment that starts or ends a region, as in "// region: setup", "# endregion: setup"
+// or "<!-- endregion -->". The name is empty for an end marker without one.
+func regionMarker(line string) (name string, end bool, ok bool) {
+	text := strings.TrimSpace(line)
+	opened := false
+	for _, opening := range commentOpenings {
+		if strings.HasPrefix(text, opening) {
+			text = strings.TrimPrefix(text, opening)
+			opened = true
+			break
+		}
+	}
+	if !opened {
+		return "", false, false
+	}
+
+	for _, closing := range commentClosings {
+		text = strings.TrimSuffix(strings.TrimSpace(text), closing)
+	}
+	text = strings.TrimSpace(text)
+
+	if rest, found := strings.CutPrefix(text, "endregion"); found {
+		if len(rest) == 0 {
+			return "", true, true
+		}
+		if rest, found = strings.CutPrefix(rest, ":"); found && len(strings.TrimSpace(rest)) > 0 {
+			return strings.TrimSpace(rest), true, true
+		}
+		return "", false, false
+	}
+
+	if rest, found := strings.CutPrefix(text, "region:"); found && len(strings.TrimSpace(rest)) > 0 {
+		return strings.TrimSpace(rest), false, true
+	}
+	return "", false, false
+}
+
+// Removes the leading spaces shared by every line that isn't blank.
+func dedent(lines []string) []string {
+	common := -1
+	for _, line := range lines {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		if indentation := len(line) - len(strings.TrimLeft(line, " ")); common < 0 || indentation < common {
+			common = indentation
+		}
+	}
+
+	if common <= 0 {
+		return lines
+	}
+
+	for i, line := range lines {
+		if len(line) >= common {
+			lines[i] = line[common:]
+		} else {
+			// a blank line shorter than the indentation
+			lines[i] = ""
+		}
+	}
+	return lines
+}
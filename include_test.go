@@ -0,0 +1,196 @@
+package mdx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const includedSource = `package main
+
+import "fmt"
+
+func main() {
+	// region: setup
+	name := "world"
+	// region: greeting
+	fmt.Printf("Hello, %s!\n", name)
+	// endregion: greeting
+	// endregion
+}
+`
+
+// Writes the files, named by their path relative to a temporary directory, returning the path of the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestParseIncludedCode(t *testing.T) {
+	input := "{ .file=src/main.go .range=3 }\n^^\n^^\n" +
+		"{ .file=src/main.go .region=setup .lines=true .highlight=9 }\n^^ ^^\n" +
+		"{ .file=src/main.go .range=5-6 .start=1 .lang=go }\n^^\n^^"
+	dir := writeFiles(t, map[string]string{"src/main.go": includedSource, "doc.mdx": input})
+
+	document, err := parseSource(context.Background(), filepath.Join(dir, "doc.mdx"), []byte(input), ParseOptions{})
+	if err != nil {
+		fail(t, err.Error())
+		t.FailNow()
+	}
+
+	expected := []Node{
+		&CodeBlock{Source: "src/main.go", StartLine: 3, Content: `import "fmt"`},
+		&CodeBlock{
+			Source:         "src/main.go",
+			LineNumbers:    true,
+			StartLine:      7,
+			HighlightLines: []LineRange{{Start: 9, End: 9}},
+			Content:        "name := \"world\"\nfmt.Printf(\"Hello, %s!\\n\", name)",
+		},
+		&CodeBlock{Source: "src/main.go", Language: "go", StartLine: 1, Content: "func main() {\n    // region: setup"},
+	}
+	if actual := clearRanges(document.Children); !reflect.DeepEqual(actual, expected) {
+		fail(t, fmt.Sprintf("Expected=%#v, got=%#v", expected, actual))
+	}
+}
+
+func TestTransformIncludedCode(t *testing.T) {
+	input := "{ .file=main.go .region=greeting .lines=true }\n^^\n^^"
+	dir := writeFiles(t, map[string]string{"main.go": includedSource, "doc.mdx": input})
+
+	actual, err := Transform(filepath.Join(dir, "doc.mdx"))
+	if err != nil {
+		fail(t, err.Error())
+		t.FailNow()
+	}
+
+	// the \n in the included code is shown as written rather than starting a new line
+	expected := "\n    <div>\n        <div class=\"code-block\">\n" +
+		"            <pre><span class=\"line-number\">9</span>fmt.Printf(\"Hello, %s!\\n\", name)</pre>\n" +
+		"        </div>\n    </div>\n"
+	if actual != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected, actual))
+	}
+}
+
+func TestIncludedCodeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.go":     includedSource,
+		"unclosed.sh": "# region: build\ngo build\n",
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{ .file=missing.go }\n^^\n^^", `2:1: Included file "missing.go" not found`},
+		{"{ .file=main.go .region=teardown }\n^^\n^^", `2:1: Region "teardown" not found in "main.go"`},
+		{"{ .file=unclosed.sh .region=build }\n^^\n^^", `2:1: Region "build" has no endregion marker in "unclosed.sh"`},
+		{"{ .file=main.go .range=10-20 }\n^^\n^^", `2:1: Range 10-20 is outside "main.go", which has 12 lines`},
+		{"{ .file=main.go .range=1,3 }\n^^\n^^", `2:1: Invalid range "1,3", expected a line number or range such as 10-20`},
+		{"{ .file=main.go .range=1 .region=setup }\n^^\n^^", `2:1: Included file "main.go" can be limited by range or by region, not both`},
+		{"Intro\n\n{ .file=main.go }\n^^\nx\n^^", `4:1: Code block including a file must be empty`},
+		{"{ .file=\"/etc/passwd\" }\n^^\n^^", `2:1: Included file "/etc/passwd" must be inside the document's directory`},
+		{"{ .file=\"../main.go\" }\n^^\n^^", `2:1: Included file "../main.go" must be inside the document's directory`},
+		{"{ .file=src/../../main.go }\n^^\n^^", `2:1: Included file "src/../../main.go" must be inside the document's directory`},
+	}
+
+	for _, test := range tests {
+		filename := filepath.Join(dir, "doc.mdx")
+		_, err := parseSource(context.Background(), filename, []byte(test.input), ParseOptions{})
+
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			fail(t, fmt.Sprintf("Expected ParseError for %q, got=%v", test.input, err))
+			continue
+		}
+		if actual := strings.SplitN(parseErr.Error(), "\n", 2)[0]; actual != filename+":"+test.expected {
+			fail(t, fmt.Sprintf("Expected error %q, got=%q", filename+":"+test.expected, actual))
+		}
+	}
+
+	// there's no directory to include files from, and the source may not be trusted to read files
+	_, err := Parse([]byte("{ .file=main.go }\n^^\n^^"))
+	expected := "2:1: Can't include \"main.go\", code is only included when transforming a file"
+	if err == nil || !strings.HasPrefix(err.Error(), expected) {
+		fail(t, fmt.Sprintf("Expected error %q, got=%v", expected, err))
+	}
+}
+
+func TestIncludedCodeThroughSymlinks(t *testing.T) {
+	outside := writeFiles(t, map[string]string{"hostname": "secret\n"})
+	dir := writeFiles(t, map[string]string{"main.go": includedSource})
+	for target, link := range map[string]string{outside: "etc", filepath.Join(outside, "hostname"): "hostname", "main.go": "latest.go"} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Skipf("Symlinks not supported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{ .file=etc/hostname }\n^^\n^^", `2:1: Included file "etc/hostname" must be inside the document's directory`},
+		{"{ .file=hostname }\n^^\n^^", `2:1: Included file "hostname" must be inside the document's directory`},
+	}
+
+	filename := filepath.Join(dir, "doc.mdx")
+	for _, test := range tests {
+		_, err := parseSource(context.Background(), filename, []byte(test.input), ParseOptions{})
+		if err == nil || strings.SplitN(err.Error(), "\n", 2)[0] != filename+":"+test.expected {
+			fail(t, fmt.Sprintf("Expected error %q, got=%v", filename+":"+test.expected, err))
+		}
+	}
+
+	// links that stay inside the directory are followed
+	document, err := parseSource(context.Background(), filename, []byte("{ .file=latest.go .range=3 }\n^^\n^^"), ParseOptions{})
+	if err != nil {
+		fail(t, err.Error())
+		t.FailNow()
+	}
+	if codeBlock := document.Children[0].(*CodeBlock); codeBlock.Content != `import "fmt"` {
+		fail(t, fmt.Sprintf("Expected the linked file's third line, got=%q", codeBlock.Content))
+	}
+}
+
+func TestRegionMarker(t *testing.T) {
+	tests := []struct {
+		line string
+		name string
+		end  bool
+		ok   bool
+	}{
+		{"// region: setup", "setup", false, true},
+		{"\t# region:  build steps ", "build steps", false, true},
+		{"<!-- region: nav -->", "nav", false, true},
+		{"/* endregion: setup */", "setup", true, true},
+		{"-- endregion", "", true, true},
+		{"// endregions", "", false, false},
+		{"// region:", "", false, false},
+		{"region: setup", "", false, false},
+		{`x := "// region: setup"`, "", false, false},
+	}
+
+	for _, test := range tests {
+		name, end, ok := regionMarker(test.line)
+		if name != test.name || end != test.end || ok != test.ok {
+			fail(t, fmt.Sprintf("Expected %q to be (%q, %t, %t), got=(%q, %t, %t)", test.line, test.name, test.end,
+				test.ok, name, end, ok))
+		}
+	}
+}
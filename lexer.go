@@ -2,41 +2,85 @@ package mdx
 
 import (
 	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// Value of lexer.ch once all of the input has been read, distinct from any character in the input.
+const endOfInput rune = -1
+
+const byteOrderMark = "\uFEFF"
+
 type lexer struct {
-	input        string
+	input string
+	// byte offsets of the current character and the one after it
 	position     int
 	readPosition int
-	ch           byte
+	ch           rune
 	prevToken    token
+	line         int
+	// column of the current character, counted in runes
+	column int
+	// byte offset of the start of the current line
+	lineOffset int
+	// number of property blocks the current character is inside, such as { .title="x" }, since values are only quoted
+	// and words only end at } within them
+	propertyDepth int
 }
 
 func newLexer(input string) *lexer {
-	l := &lexer{input: input}
+	l := &lexer{input: input, line: 1}
+	// skip a byte order mark, while still counting it in offsets so they match the source
+	if strings.HasPrefix(input, byteOrderMark) {
+		l.readPosition = len(byteOrderMark)
+		l.lineOffset = len(byteOrderMark)
+	}
 	l.readChar()
 	return l
 }
 
 func (l *lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPosition]
+	if l.ch == endOfInput {
+		return
 	}
+
+	// a carriage return ends a line unless it is the first half of a CRLF
+	if l.ch == '\n' || (l.ch == '\r' && l.peekChar() != '\n') {
+		l.line++
+		l.column = 0
+		l.lineOffset = l.readPosition
+	}
+
 	l.position = l.readPosition
-	l.readPosition++
+	l.column++
+	if l.readPosition >= len(l.input) {
+		l.ch = endOfInput
+		return
+	}
+
+	ch, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	l.ch = ch
+	l.readPosition += width
+}
+
+// Returns the position of the current character.
+func (l *lexer) currentPosition() Position {
+	return Position{Offset: l.position, Line: l.line, Column: l.column}
 }
 
 func (l *lexer) nextToken() token {
 	var tok token
+	pos := l.currentPosition()
 
 	switch l.ch {
 	case '#':
 		tok = newToken(hash, string(l.ch))
 	case '{':
+		l.propertyDepth++
 		tok = newToken(lsquirly, string(l.ch))
 	case '}':
+		l.propertyDepth = max(l.propertyDepth-1, 0)
 		tok = newToken(rsquirly, string(l.ch))
 	case '(':
 		tok = newToken(lparen, string(l.ch))
@@ -52,6 +96,12 @@ func (l *lexer) nextToken() token {
 		tok = newToken(tab, "\\t")
 	case '\n':
 		tok = newToken(newline, "\\n")
+	case '\r':
+		// CRLF and a lone CR are line breaks just like LF
+		if l.peekChar() == '\n' {
+			l.readChar()
+		}
+		tok = newToken(newline, "\\n")
 	case '`':
 		tok = newToken(backtick, string(l.ch))
 	case '*':
@@ -72,6 +122,8 @@ func (l *lexer) nextToken() token {
 		tok = newToken(backslash, string(l.ch))
 	case '@':
 		tok = newToken(at, string(l.ch))
+	case '|':
+		tok = newToken(pipe, string(l.ch))
 	case '=':
 		tok = newToken(equals, string(l.ch))
 	case '~':
@@ -82,19 +134,21 @@ func (l *lexer) nextToken() token {
 		tok = newToken(caret, string(l.ch))
 	case ' ':
 		tok = newToken(space, string(l.ch))
-	case 0:
+	case endOfInput:
 		tok = newToken(eof, "")
 	default:
-		if isDigit(l.ch) {
+		if isQuote(l.ch) && l.prevToken.Type == equals && l.propertyDepth > 0 {
+			tok = newToken(quoted, l.readQuoted())
+		} else if isDigit(l.ch) {
 			numberBuffer := bytes.Buffer{}
-			numberBuffer.WriteByte(l.ch)
+			numberBuffer.WriteRune(l.ch)
 			for isDigit(l.peekChar()) {
 				l.readChar()
-				numberBuffer.WriteByte(l.ch)
+				numberBuffer.WriteRune(l.ch)
 			}
 
 			if l.peekChar() == '.' {
-				if l.prevToken.Type == newline || l.prevToken.Type == "" {
+				if isIndentation(l.input[l.lineOffset:pos.Offset]) {
 					tok = newToken(listelement, numberBuffer.String()+".")
 					l.readChar()
 					l.readChar()
@@ -109,45 +163,95 @@ func (l *lexer) nextToken() token {
 			}
 		} else {
 			wordToken := l.readWord()
+			if len(wordToken) == 0 {
+				// a character that ends words without having a token of its own would otherwise stall the lexer
+				l.readChar()
+				wordToken = l.input[pos.Offset:l.position]
+			}
 			tok = newToken(word, wordToken)
 		}
+		tok.Pos = pos
+		tok.End = l.currentPosition()
 		l.prevToken = tok
 		return tok
 	}
 
 	l.readChar()
+	tok.Pos = pos
+	tok.End = l.currentPosition()
 	l.prevToken = tok
 	return tok
 }
 
-func (l *lexer) peekChar() byte {
+// Reports whether s, the text before a token on its line, is only spaces and tabs.
+func isIndentation(s string) bool {
+	return len(strings.Trim(s, " \t")) == 0
+}
+
+func (l *lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
-		return 0
+		return endOfInput
 	} else {
-		return l.input[l.readPosition]
+		ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return ch
 	}
 }
 
-func isWhitespace(ch byte) bool {
+func isWhitespace(ch rune) bool {
 	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
 
-func isClosingPair(ch byte) bool {
+func isClosingPair(ch rune) bool {
 	return ch == ']' || ch == ')' || ch == '>' || ch == '*' || ch == '`' || ch == '$' || ch == '^'
 }
 
 func (l *lexer) readWord() string {
 	position := l.position
-	for !isWhitespace(l.ch) && !isClosingPair(l.ch) && l.ch != '=' && l.ch != 0 && l.ch != '\\' {
+	for !isWhitespace(l.ch) && !isClosingPair(l.ch) && l.ch != '=' && l.ch != endOfInput && l.ch != '\\' {
+		if (l.ch == '|' && l.onTableLine()) || (l.ch == '}' && l.propertyDepth > 0) {
+			break
+		}
+		l.readChar()
+	}
+	return l.input[position:l.position]
+}
+
+// Reports whether the current line starts with a pipe, as table rows do, so that pipes in it end words.
+func (l *lexer) onTableLine() bool {
+	return strings.HasPrefix(strings.TrimLeft(l.input[l.lineOffset:], " \t"), "|")
+}
+
+// Reads a quoted property value, quotes included, up to the matching quote or the end of the line.
+// A backslash escapes the character after it, so the value can contain its own quote.
+func (l *lexer) readQuoted() string {
+	position := l.position
+	quote := l.ch
+	l.readChar()
+	for l.ch != quote && l.ch != '\n' && l.ch != '\r' && l.ch != endOfInput {
+		if l.ch == '\\' && !isLineEnd(l.peekChar()) {
+			l.readChar()
+		}
+		l.readChar()
+	}
+
+	if l.ch == quote {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch == '-'
+func isQuote(ch rune) bool {
+	return ch == '"' || ch == '\''
+}
+
+func isLineEnd(ch rune) bool {
+	return ch == '\n' || ch == '\r' || ch == endOfInput
+}
+
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_' || ch == '-'
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
@@ -98,3 +98,219 @@ Paragraph section
 		}
 	}
 }
+
+func TestLexerPositions(t *testing.T) {
+	input := "# Hi\n\tthere 12. x\n{ .a=b }"
+
+	expectedPositions := []struct {
+		expectedLiteral string
+		expectedPos     Position
+	}{
+		{"#", Position{Offset: 0, Line: 1, Column: 1}},
+		{" ", Position{Offset: 1, Line: 1, Column: 2}},
+		{"Hi", Position{Offset: 2, Line: 1, Column: 3}},
+		{"\\n", Position{Offset: 4, Line: 1, Column: 5}},
+		{"\\t", Position{Offset: 5, Line: 2, Column: 1}},
+		{"there", Position{Offset: 6, Line: 2, Column: 2}},
+		{" ", Position{Offset: 11, Line: 2, Column: 7}},
+		{"12", Position{Offset: 12, Line: 2, Column: 8}},
+		{".", Position{Offset: 14, Line: 2, Column: 10}},
+		{" ", Position{Offset: 15, Line: 2, Column: 11}},
+		{"x", Position{Offset: 16, Line: 2, Column: 12}},
+		{"\\n", Position{Offset: 17, Line: 2, Column: 13}},
+		{"{", Position{Offset: 18, Line: 3, Column: 1}},
+		{" ", Position{Offset: 19, Line: 3, Column: 2}},
+		{".", Position{Offset: 20, Line: 3, Column: 3}},
+		{"a", Position{Offset: 21, Line: 3, Column: 4}},
+		{"=", Position{Offset: 22, Line: 3, Column: 5}},
+		{"b", Position{Offset: 23, Line: 3, Column: 6}},
+		{" ", Position{Offset: 24, Line: 3, Column: 7}},
+		{"}", Position{Offset: 25, Line: 3, Column: 8}},
+		{"", Position{Offset: 26, Line: 3, Column: 9}},
+	}
+
+	l := newLexer(input)
+
+	for _, expected := range expectedPositions {
+		actual := l.nextToken()
+
+		if actual.Literal != expected.expectedLiteral {
+			t.Fatalf("Incorrect token literal. Expected=%q, got=%q", expected.expectedLiteral, actual.Literal)
+		}
+
+		if actual.Pos != expected.expectedPos {
+			t.Fatalf("Incorrect position for %q. Expected=%+v, got=%+v", actual.Literal, expected.expectedPos, actual.Pos)
+		}
+	}
+}
+
+func TestLexerUnicodePositions(t *testing.T) {
+	input := "\uFEFF# Grüße 日本\n{ .title=🎉 }"
+
+	expectedPositions := []struct {
+		expectedLiteral string
+		expectedPos     Position
+	}{
+		{"#", Position{Offset: 3, Line: 1, Column: 1}},
+		{" ", Position{Offset: 4, Line: 1, Column: 2}},
+		{"Grüße", Position{Offset: 5, Line: 1, Column: 3}},
+		{" ", Position{Offset: 12, Line: 1, Column: 8}},
+		{"日本", Position{Offset: 13, Line: 1, Column: 9}},
+		{"\\n", Position{Offset: 19, Line: 1, Column: 11}},
+		{"{", Position{Offset: 20, Line: 2, Column: 1}},
+		{" ", Position{Offset: 21, Line: 2, Column: 2}},
+		{".", Position{Offset: 22, Line: 2, Column: 3}},
+		{"title", Position{Offset: 23, Line: 2, Column: 4}},
+		{"=", Position{Offset: 28, Line: 2, Column: 9}},
+		{"🎉", Position{Offset: 29, Line: 2, Column: 10}},
+		{" ", Position{Offset: 33, Line: 2, Column: 11}},
+		{"}", Position{Offset: 34, Line: 2, Column: 12}},
+		{"", Position{Offset: 35, Line: 2, Column: 13}},
+	}
+
+	l := newLexer(input)
+
+	for _, expected := range expectedPositions {
+		actual := l.nextToken()
+
+		if actual.Literal != expected.expectedLiteral {
+			t.Fatalf("Incorrect token literal. Expected=%q, got=%q", expected.expectedLiteral, actual.Literal)
+		}
+
+		if actual.Pos != expected.expectedPos {
+			t.Fatalf("Incorrect position for %q. Expected=%+v, got=%+v", actual.Literal, expected.expectedPos, actual.Pos)
+		}
+	}
+}
+
+func TestLexerLineEndings(t *testing.T) {
+	input := "a\r\nb\rc\n"
+
+	expectedTokens := []struct {
+		expectedType tokenType
+		expectedPos  Position
+		expectedEnd  Position
+	}{
+		{word, Position{Offset: 0, Line: 1, Column: 1}, Position{Offset: 1, Line: 1, Column: 2}},
+		{newline, Position{Offset: 1, Line: 1, Column: 2}, Position{Offset: 3, Line: 2, Column: 1}},
+		{word, Position{Offset: 3, Line: 2, Column: 1}, Position{Offset: 4, Line: 2, Column: 2}},
+		{newline, Position{Offset: 4, Line: 2, Column: 2}, Position{Offset: 5, Line: 3, Column: 1}},
+		{word, Position{Offset: 5, Line: 3, Column: 1}, Position{Offset: 6, Line: 3, Column: 2}},
+		{newline, Position{Offset: 6, Line: 3, Column: 2}, Position{Offset: 7, Line: 4, Column: 1}},
+		{eof, Position{Offset: 7, Line: 4, Column: 1}, Position{Offset: 7, Line: 4, Column: 1}},
+	}
+
+	l := newLexer(input)
+
+	for _, expected := range expectedTokens {
+		actual := l.nextToken()
+
+		if actual.Type != expected.expectedType {
+			t.Fatalf("Incorrect token type. Expected=%q, got=%q", expected.expectedType, actual.Type)
+		}
+
+		if actual.Pos != expected.expectedPos || actual.End != expected.expectedEnd {
+			t.Fatalf("Incorrect range for %q. Expected=%+v-%+v, got=%+v-%+v", actual.Literal, expected.expectedPos, expected.expectedEnd, actual.Pos, actual.End)
+		}
+	}
+}
+
+func TestLexerQuotedValues(t *testing.T) {
+	input := `{ .a="b \" c" .d='e' "f" } x="g"`
+
+	expectedTokens := []struct {
+		expectedType    tokenType
+		expectedLiteral string
+	}{
+		{lsquirly, "{"},
+		{space, " "},
+		{dot, "."},
+		{word, "a"},
+		{equals, "="},
+		{quoted, `"b \" c"`},
+		{space, " "},
+		{dot, "."},
+		{word, "d"},
+		{equals, "="},
+		{quoted, "'e'"},
+		{space, " "},
+		{word, `"f"`},
+		{space, " "},
+		{rsquirly, "}"},
+		// values are only quoted inside a property block
+		{space, " "},
+		{word, "x"},
+		{equals, "="},
+		{word, `"g"`},
+		{eof, ""},
+	}
+
+	l := newLexer(input)
+
+	for _, expected := range expectedTokens {
+		actual := l.nextToken()
+
+		if actual.Type != expected.expectedType || actual.Literal != expected.expectedLiteral {
+			t.Fatalf("Incorrect token. Expected=%q %q, got=%q %q", expected.expectedType, expected.expectedLiteral, actual.Type, actual.Literal)
+		}
+	}
+}
+
+func TestLexerIndentedListElement(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType tokenType
+	}{
+		{"  1. a", listelement},
+		{"\t\t1. a", listelement},
+		{"a 1. b", word},
+	}
+
+	for _, test := range tests {
+		l := newLexer(test.input)
+		tok := l.nextToken()
+		for tok.Type == space || tok.Type == tab || tok.Literal == "a" {
+			tok = l.nextToken()
+		}
+
+		if tok.Type != test.expectedType {
+			t.Fatalf("Incorrect token type for %q. Expected=%q, got=%q", test.input, test.expectedType, tok.Type)
+		}
+	}
+}
+
+func TestLexerPipes(t *testing.T) {
+	input := "| a|b |\na|b  | c"
+
+	expectedTokens := []struct {
+		expectedType    tokenType
+		expectedLiteral string
+	}{
+		{pipe, "|"},
+		{space, " "},
+		{word, "a"},
+		{pipe, "|"},
+		{word, "b"},
+		{space, " "},
+		{pipe, "|"},
+		{newline, "\\n"},
+		// pipes only end words on lines that start with one, like table rows
+		{word, "a|b"},
+		{space, " "},
+		{space, " "},
+		{pipe, "|"},
+		{space, " "},
+		{word, "c"},
+		{eof, ""},
+	}
+
+	l := newLexer(input)
+
+	for _, expected := range expectedTokens {
+		actual := l.nextToken()
+
+		if actual.Type != expected.expectedType || actual.Literal != expected.expectedLiteral {
+			t.Fatalf("Incorrect token. Expected=%q %q, got=%q %q", expected.expectedType, expected.expectedLiteral, actual.Type, actual.Literal)
+		}
+	}
+}
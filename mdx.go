@@ -1,6 +1,9 @@
 package mdx
 
 import (
+	"context"
+	"errors"
+	"io"
 	"os"
 	"strings"
 )
@@ -13,6 +16,70 @@ func (e *invalidFileError) Error() string {
 	return "Invalid file type. File must have .md or .mdx extension"
 }
 
+// ErrInputTooLarge is returned when the source is longer than ParseOptions.MaxInputBytes.
+var ErrInputTooLarge = errors.New("Input is larger than the maximum allowed size")
+
+// ParseOptions limits the work done parsing a document, so that input from untrusted sources can't exhaust the
+// stack, memory or CPU. A limit of zero means there is no limit.
+type ParseOptions struct {
+	// Maximum depth that elements can be nested inside each other
+	MaxDepth int
+	// Maximum length of the source in bytes
+	MaxInputBytes int
+	// Maximum number of nodes the parsed document can contain
+	MaxNodes int
+}
+
+// TransformOptions controls how TransformContext parses and renders its input.
+type TransformOptions struct {
+	ParseOptions
+	// Render in safe mode, restricting attributes, URLs and buttons. Nil renders everything.
+	Safe *SafeOptions
+	// Properties added to every element of a kind, see HtmlRenderer.DefaultProperties
+	DefaultProperties map[string][]Property
+	// Write text and code block content without escaping, see HtmlRenderer.AllowRawHtml
+	AllowRawHtml bool
+}
+
+// Parse MDX source into a Document.
+// On successful parse, returns the document and nil error.
+// On failure returns nil document with non nil error.
+func Parse(src []byte) (*Document, error) {
+	return parseSource(context.Background(), "", src, ParseOptions{})
+}
+
+// Parse MDX source into a Document, failing if the source goes beyond any of the limits in opts.
+// On successful parse, returns the document and nil error.
+// On failure returns nil document with non nil error.
+func ParseWithOptions(src []byte, opts ParseOptions) (*Document, error) {
+	return parseSource(context.Background(), "", src, opts)
+}
+
+// Parses src within the limits of opts, reporting filename in any ParseError.
+// Parsing stops early with the context's error if ctx is done.
+func parseSource(ctx context.Context, filename string, src []byte, opts ParseOptions) (*Document, error) {
+	if opts.MaxInputBytes > 0 && len(src) > opts.MaxInputBytes {
+		return nil, ErrInputTooLarge
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	lexer := newLexer(string(src))
+	parser := newParser(lexer)
+	parser.filename = filename
+	parser.opts = opts
+	parser.ctx = ctx
+	elements, parseErr := parser.parse(eof)
+
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	return &Document{Children: elements, Warnings: parser.warnings}, nil
+}
+
 // Transform .mdx or .md file into HTML string.
 // On successful transformation, returns string representing HTML and nil error.
 // On failure returns empty string with non nil error.
@@ -26,19 +93,66 @@ func Transform(inputFilename string) (string, error) {
 		return "", readErr
 	}
 
-	lexer := newLexer(string(data))
-	parser := newParser(lexer)
-	elements, parseErr := parser.parse(eof)
+	document, parseErr := parseSource(context.Background(), inputFilename, data, ParseOptions{})
+	if parseErr != nil {
+		return "", parseErr
+	}
+
+	return document.Html(), nil
+}
 
+// Transform MDX source string into HTML string.
+// On successful transformation, returns string representing HTML and nil error.
+// On failure returns empty string with non nil error.
+func TransformString(src string) (string, error) {
+	return TransformBytes([]byte(src))
+}
+
+// Transform MDX source bytes into HTML string.
+// On successful transformation, returns string representing HTML and nil error.
+// On failure returns empty string with non nil error.
+func TransformBytes(src []byte) (string, error) {
+	document, parseErr := Parse(src)
 	if parseErr != nil {
 		return "", parseErr
 	}
 
-	htmlString := transformMDX(elements)
+	htmlString := document.Html()
 
 	return htmlString, nil
 }
 
+// Transform MDX source read from r into HTML string. The reader is consumed until EOF.
+// On successful transformation, returns string representing HTML and nil error.
+// On failure returns empty string with non nil error.
+func TransformReader(r io.Reader) (string, error) {
+	data, readErr := io.ReadAll(r)
+	if readErr != nil {
+		return "", readErr
+	}
+
+	return TransformBytes(data)
+}
+
+// Transform MDX source bytes into HTML string, within the limits and safe mode settings of opts.
+// Parsing and rendering stop as soon as ctx is done, returning the context's error.
+// On successful transformation, returns string representing HTML and nil error.
+// On failure returns empty string with non nil error.
+func TransformContext(ctx context.Context, src []byte, opts TransformOptions) (string, error) {
+	document, parseErr := parseSource(ctx, "", src, opts.ParseOptions)
+	if parseErr != nil {
+		return "", parseErr
+	}
+
+	var sb strings.Builder
+	_, renderErr := renderHtml(ctx, &sb, &Div{Children: document.Children}, 1, &HtmlRenderer{Safe: opts.Safe, DefaultProperties: opts.DefaultProperties, AllowRawHtml: opts.AllowRawHtml})
+	if renderErr != nil {
+		return "", renderErr
+	}
+
+	return sb.String(), nil
+}
+
 // Generates HTML file based on the given configuration object.
 // On successful generation, returns number of bytes written to file and nil error.
 // On failure returns bytes written with non nil error.
@@ -52,15 +166,12 @@ func Generate(config *GeneratorConfig) (int, error) {
 		return 0, readErr
 	}
 
-	lexer := newLexer(string(data))
-	parser := newParser(lexer)
-	elements, parseErr := parser.parse(eof)
-
+	document, parseErr := parseSource(context.Background(), config.InputFilename, data, ParseOptions{})
 	if parseErr != nil {
 		return 0, parseErr
 	}
 
-	n, err := generateHtml(elements, config)
+	n, err := generateHtml(document.Children, config)
 
 	return n, err
 }
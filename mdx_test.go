@@ -0,0 +1,266 @@
+package mdx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := `{ .class=title }
+# Hello
+[
+	Visit [MDX](https://github.com/mjbozo/mdx)
+]`
+	document, err := Parse([]byte(input))
+	if err != nil {
+		fail(t, err.Error())
+		t.FailNow()
+	}
+
+	validateLength(t, len(document.Children), 2)
+
+	if heading, ok := document.Children[0].(*Heading); ok {
+		validateLength(t, len(heading.Properties), 1)
+		if heading.Properties[0] != (Property{Name: "class", Value: "title"}) {
+			fail(t, fmt.Sprintf("Expected Heading property class=title, got=%v", heading.Properties[0]))
+		}
+	} else {
+		fail(t, fmt.Sprintf("Expected Heading, got=%T", document.Children[0]))
+	}
+
+	if div, ok := document.Children[1].(*Div); ok {
+		validateLength(t, len(div.Children), 1)
+		p, ok := div.Children[0].(*Paragraph)
+		if !ok {
+			fail(t, fmt.Sprintf("Expected Paragraph, got=%T", div.Children[0]))
+			t.FailNow()
+		}
+
+		validateLength(t, len(p.Content), 2)
+		if link, ok := p.Content[1].(*Link); ok {
+			if link.Url != "https://github.com/mjbozo/mdx" {
+				fail(t, fmt.Sprintf("Expected Link url, got=%s", link.Url))
+			}
+		} else {
+			fail(t, fmt.Sprintf("Expected Link, got=%T", p.Content[1]))
+		}
+	} else {
+		fail(t, fmt.Sprintf("Expected Div, got=%T", document.Children[1]))
+	}
+}
+
+func TestDocumentHtml(t *testing.T) {
+	document, err := Parse([]byte("# Hello"))
+	if err != nil {
+		fail(t, err.Error())
+		t.FailNow()
+	}
+
+	expected := "\n    <div>\n        <h1>Hello</h1>\n    </div>\n"
+	if actual := document.Html(); actual != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected, actual))
+	}
+}
+
+func TestTransformSources(t *testing.T) {
+	input := "# Hello\n{ .class=greeting } Hi there"
+	expected := "\n    <div>\n        <h1>Hello</h1>\n        <p class=\"greeting\">Hi there</p>\n    </div>\n"
+
+	fromString, err := TransformString(input)
+	if err != nil {
+		fail(t, err.Error())
+	}
+
+	fromBytes, err := TransformBytes([]byte(input))
+	if err != nil {
+		fail(t, err.Error())
+	}
+
+	fromReader, err := TransformReader(strings.NewReader(input))
+	if err != nil {
+		fail(t, err.Error())
+	}
+
+	for _, actual := range []string{fromString, fromBytes, fromReader} {
+		if actual != expected {
+			fail(t, fmt.Sprintf("Expected %q, got=%q", expected, actual))
+		}
+	}
+}
+
+type failingReader struct{}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestTransformReaderError(t *testing.T) {
+	htmlString, err := TransformReader(&failingReader{})
+	if err == nil {
+		fail(t, "Expected read error, got=nil")
+	}
+
+	if htmlString != "" {
+		fail(t, fmt.Sprintf("Expected empty output, got=%q", htmlString))
+	}
+}
+
+func TestTransformParseErrorFilename(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "broken.mdx")
+	if err := os.WriteFile(filename, []byte("Intro\n\n{ .=value }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Transform(filename)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		fail(t, fmt.Sprintf("Expected ParseError, got=%T", err))
+		t.FailNow()
+	}
+
+	expected := filename + ":3:4: Property formatted incorrectly. DOT must be followed by a WORD\n{ .=value }\n   ^"
+	if parseErr.Error() != expected {
+		fail(t, fmt.Sprintf("Expected error %q, got=%q", expected, parseErr.Error()))
+	}
+}
+
+func TestParseOptionsLimits(t *testing.T) {
+	inputs := []struct {
+		input string
+		opts  ParseOptions
+	}{
+		{strings.Repeat("[\n", 100000), ParseOptions{MaxDepth: 64}},
+		{strings.Repeat(">", 100000) + " deep", ParseOptions{MaxDepth: 64}},
+		{strings.Repeat("[", 100000), ParseOptions{MaxDepth: 64}},
+		{strings.Repeat("$ *a* ", 100000), ParseOptions{MaxDepth: 64, MaxNodes: 1000}},
+		{strings.Repeat("- item\n", 1000), ParseOptions{MaxNodes: 100}},
+	}
+
+	for _, test := range inputs {
+		_, err := ParseWithOptions([]byte(test.input), test.opts)
+		if _, ok := err.(*ParseError); !ok {
+			fail(t, fmt.Sprintf("Expected ParseError for %.20q, got=%v", test.input, err))
+		}
+	}
+
+	_, err := ParseWithOptions([]byte("# Hello"), ParseOptions{MaxInputBytes: 4})
+	if !errors.Is(err, ErrInputTooLarge) {
+		fail(t, fmt.Sprintf("Expected ErrInputTooLarge, got=%v", err))
+	}
+}
+
+func TestParseOptionsWithinLimits(t *testing.T) {
+	input := "[\n  [\n    > quote\n    >> nested\n  ]\n]"
+	opts := ParseOptions{MaxDepth: 5, MaxInputBytes: len(input), MaxNodes: 10}
+	document, err := ParseWithOptions([]byte(input), opts)
+	if err != nil {
+		fail(t, err.Error())
+		t.FailNow()
+	}
+
+	expected, _ := Parse([]byte(input))
+	if document.Html() != expected.Html() {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected.Html(), document.Html()))
+	}
+}
+
+func TestTransformContext(t *testing.T) {
+	htmlString, err := TransformContext(context.Background(), []byte("# Hello"), TransformOptions{})
+	if err != nil {
+		fail(t, err.Error())
+	}
+
+	expected, _ := TransformString("# Hello")
+	if htmlString != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected, htmlString))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = TransformContext(ctx, []byte("# Hello"), TransformOptions{})
+	if !errors.Is(err, context.Canceled) {
+		fail(t, fmt.Sprintf("Expected context.Canceled, got=%v", err))
+	}
+}
+
+func TestTransformEscapesHtml(t *testing.T) {
+	htmlString, err := TransformString("Fish & chips < 5 dollars\n\n{ .title=a\"b } `x<y>`")
+	if err != nil {
+		fail(t, err.Error())
+	}
+
+	expected := "\n    <div>\n        <p>Fish &amp; chips &lt; 5 dollars</p>\n        <code title=\"a&#34;b\">x&lt;y&gt;</code>\n    </div>\n"
+	if htmlString != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected, htmlString))
+	}
+}
+
+func TestTransformContextAllowRawHtml(t *testing.T) {
+	src := []byte("Fish & chips > 5 dollars")
+	htmlString, err := TransformContext(context.Background(), src, TransformOptions{AllowRawHtml: true})
+	if err != nil {
+		fail(t, err.Error())
+	}
+
+	expected := "\n    <div>\n        <p>Fish & chips > 5 dollars</p>\n    </div>\n"
+	if htmlString != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected, htmlString))
+	}
+
+	// safe mode still escapes
+	htmlString, err = TransformContext(context.Background(), src, TransformOptions{AllowRawHtml: true, Safe: &SafeOptions{}})
+	if err != nil {
+		fail(t, err.Error())
+	}
+
+	expected = "\n    <div>\n        <p>Fish &amp; chips &gt; 5 dollars</p>\n    </div>\n"
+	if htmlString != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected, htmlString))
+	}
+}
+
+func TestTransformContextSafe(t *testing.T) {
+	src := []byte("{ .onmouseover=alert(1) .class=a }\n# Hello\n\n[x](javascript:alert(1))")
+	htmlString, err := TransformContext(context.Background(), src, TransformOptions{Safe: &SafeOptions{}})
+	if err != nil {
+		fail(t, err.Error())
+	}
+
+	if strings.Contains(htmlString, "onmouseover") || strings.Contains(htmlString, "javascript:") {
+		fail(t, fmt.Sprintf("Expected unsafe attributes and URLs to be removed, got=%q", htmlString))
+	}
+
+	if !strings.Contains(htmlString, "<h1 class=\"a\">Hello</h1>") || !strings.Contains(htmlString, "href=\"#\"") {
+		fail(t, fmt.Sprintf("Expected safe attributes and links to remain, got=%q", htmlString))
+	}
+}
+
+func TestTransformSelectorProperties(t *testing.T) {
+	htmlString, err := TransformString("{ #intro .card.highlight .hidden }\n# Hello")
+	if err != nil {
+		fail(t, err.Error())
+	}
+
+	expected := "\n    <div>\n        <h1 id=\"intro\" class=\"card highlight\" hidden>Hello</h1>\n    </div>\n"
+	if htmlString != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected, htmlString))
+	}
+}
+
+func TestTransformContextDefaultProperties(t *testing.T) {
+	opts := TransformOptions{DefaultProperties: map[string][]Property{"p": {{Name: "class", Value: "lead"}}}}
+	htmlString, err := TransformContext(context.Background(), []byte("{ .class=big }\nHello\n\nWorld"), opts)
+	if err != nil {
+		fail(t, err.Error())
+	}
+
+	if !strings.Contains(htmlString, "<p class=\"lead big\">Hello</p>") || !strings.Contains(htmlString, "<p class=\"lead\">World</p>") {
+		fail(t, fmt.Sprintf("Expected default classes on every paragraph, got=%q", htmlString))
+	}
+}
@@ -1,18 +1,39 @@
 package mdx
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 )
 
 type parser struct {
 	lex           *lexer
+	filename      string
 	previousToken token
 	currentTok    token
 	nextTok       token
+	// end of the last consumed token that wasn't a line break
+	contentEnd Position
+	// first error from a nested parse, returned once parsing unwinds
+	err error
+	// problems that don't stop parsing, such as duplicate properties
+	warnings []Warning
+	// shortest fence of each character known to be unclosed, see codeFenceAt
+	unclosedFences map[byte]unclosedFence
+
+	// limits and cancellation, which are unset for parsers created directly by newParser
+	opts       ParseOptions
+	ctx        context.Context
+	depth      int
+	nodeCount  int
+	tokenCount int
 }
 
+// How many tokens are read between checks of the parser's context.
+const contextCheckInterval = 1024
+
 func newParser(lex *lexer) *parser {
 	parser := &parser{lex: lex}
 	parser.nextToken()
@@ -20,19 +41,128 @@ func newParser(lex *lexer) *parser {
 	return parser
 }
 
-type parseError struct {
-	error
-	errorReason string
+// ParseError describes malformed MDX and where in the source it was found.
+type ParseError struct {
+	// Name of the file being parsed, empty when parsing from memory
+	Filename string
+	Pos      Position
+	Reason   string
+	// Source line containing the error
+	Snippet string
+}
+
+func (e *ParseError) Error() string {
+	location := e.Pos.String()
+	if len(e.Filename) > 0 {
+		location = e.Filename + ":" + location
+	}
+
+	// keep tabs in the caret padding so it lines up under the snippet
+	var padding strings.Builder
+	column := 1
+	for _, ch := range e.Snippet {
+		if column >= e.Pos.Column {
+			break
+		}
+
+		if ch == '\t' {
+			padding.WriteRune('\t')
+		} else {
+			padding.WriteRune(' ')
+		}
+		column++
+	}
+
+	return fmt.Sprintf("%s: %s\n%s\n%s^", location, e.Reason, e.Snippet, padding.String())
+}
+
+// Warning describes MDX that parsed successfully but may not render as intended.
+type Warning struct {
+	// Name of the file being parsed, empty when parsing from memory
+	Filename string
+	Pos      Position
+	Message  string
+}
+
+func (w Warning) String() string {
+	location := w.Pos.String()
+	if len(w.Filename) > 0 {
+		location = w.Filename + ":" + location
+	}
+	return location + ": " + w.Message
+}
+
+// Returns a ParseError located at tok.
+func (p *parser) errorAt(tok token, reason string) *ParseError {
+	input := p.lex.input
+	lineStart := strings.LastIndexAny(input[:tok.Pos.Offset], "\r\n") + 1
+	if lineStart == 0 {
+		lineStart = len(input) - len(strings.TrimPrefix(input, byteOrderMark))
+	}
+	lineEnd := strings.IndexAny(input[lineStart:], "\r\n")
+	if lineEnd < 0 {
+		lineEnd = len(input)
+	} else {
+		lineEnd += lineStart
+	}
+
+	return &ParseError{Filename: p.filename, Pos: tok.Pos, Reason: reason, Snippet: input[lineStart:lineEnd]}
+}
+
+// Records the first error found while parsing the content of an element.
+// The rest of the input is skipped once an error is recorded, so every parse loop stops and parse returns it.
+func (p *parser) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// Increases the nesting depth, failing when it goes beyond the maximum.
+// Returns false if the caller should stop parsing, in which case it must not call leave.
+func (p *parser) enter() bool {
+	if p.opts.MaxDepth > 0 && p.depth >= p.opts.MaxDepth {
+		p.fail(p.errorAt(p.currentTok, fmt.Sprintf("Element is nested more than %d levels deep", p.opts.MaxDepth)))
+		return false
+	}
+
+	p.depth++
+	return true
 }
 
-func (p *parseError) Error() string {
-	return fmt.Sprintf("ParseError occurred: %s", p.errorReason)
+func (p *parser) leave() {
+	p.depth--
+}
+
+// Counts n parsed nodes, failing when there are more than the maximum.
+func (p *parser) countNodes(n int) {
+	p.nodeCount += n
+	if p.opts.MaxNodes > 0 && p.nodeCount > p.opts.MaxNodes {
+		p.fail(p.errorAt(p.currentTok, fmt.Sprintf("Document contains more than %d nodes", p.opts.MaxNodes)))
+	}
 }
 
 func (p *parser) nextToken() {
+	if !(p.curTokenIs(newline) || p.curTokenIs(eof)) {
+		p.contentEnd = p.currentTok.End
+	}
+
+	if p.ctx != nil {
+		p.tokenCount++
+		if p.tokenCount%contextCheckInterval == 0 {
+			if err := p.ctx.Err(); err != nil {
+				p.fail(err)
+			}
+		}
+	}
+
 	p.previousToken = p.currentTok
 	p.currentTok = p.nextTok
-	p.nextTok = p.lex.nextToken()
+	if p.err != nil {
+		// pretend the input ends here so that whatever is being parsed finishes quickly
+		p.nextTok = token{Type: eof, Pos: p.nextTok.Pos, End: p.nextTok.Pos}
+	} else {
+		p.nextTok = p.lex.nextToken()
+	}
 }
 
 func (p *parser) peekToken() *token {
@@ -55,16 +185,45 @@ func (p *parser) isAfterNewline(tokType tokenType) bool {
 	return p.curTokenIs(newline) && p.peekTokenIs(tokType)
 }
 
+// Reports whether the current token ends a line and the next line starts a list item, however far it is indented.
+func (p *parser) isNextLineListItem() bool {
+	if !p.curTokenIs(newline) {
+		return false
+	}
+	_, ok := listMarkerAt(p.lex.input, p.nextTok.Pos.Offset)
+	return ok
+}
+
+// Reports whether the current token ends a line and the next line starts a block element, however far it is indented.
 func (p *parser) isNextLineBlockElement() bool {
-	return p.curTokenIs(newline) && p.peekToken().IsElementToken() && p.peekToken().IsBlockElement()
+	if !p.curTokenIs(newline) {
+		return false
+	}
+
+	next := p.nextTok
+	contentStart := next.Pos.Offset
+	if next.Type == space || next.Type == tab {
+		_, contentStart = indentationAt(p.lex.input, next.Pos.Offset)
+		next = newLexer(p.lex.input[contentStart:]).nextToken()
+	}
+
+	switch next.Type {
+	case pipe:
+		_, ok := p.tableAlignments(contentStart)
+		return ok
+	case backtick, tidle:
+		_, ok := p.codeFenceAt(contentStart)
+		return ok
+	}
+	return next.IsElementToken() && next.IsBlockElement()
 }
 
-func (p *parser) parse(delim tokenType) ([]component, error) {
-	elements := make([]component, 0)
-	var properties []property
-	var component component
+func (p *parser) parse(delim tokenType) ([]Node, error) {
+	elements := make([]Node, 0)
+	var properties []Property
+	var component Node
 
-	for p.currentTok.Type != delim && p.currentTok.Type != eof {
+	for p.currentTok.Type != delim && p.currentTok.Type != eof && p.err == nil {
 		if p.currentTok.Type == lsquirly {
 			var err error
 			properties, err, _ = p.parseProperties()
@@ -88,13 +247,33 @@ func (p *parser) parse(delim tokenType) ([]component, error) {
 		p.nextToken()
 	}
 
+	if p.err != nil {
+		return nil, p.err
+	}
+
 	return elements, nil
 }
 
-func (p *parser) parseComponent(properties []property, closing tokenType, joinPrevious bool) component {
+// Returns the range from start to the end of the last consumed token.
+func (p *parser) rangeFrom(start Position) SourceRange {
+	return SourceRange{Start: start, End: p.contentEnd}
+}
+
+// Returns the range from start to the end of the current token, for elements that finish on their closing token.
+func (p *parser) rangeThrough(start Position) SourceRange {
+	return SourceRange{Start: start, End: p.currentTok.End}
+}
+
+func (p *parser) parseComponent(properties []Property, closing tokenType, joinPrevious bool) Node {
+	if !p.enter() {
+		return nil
+	}
+	defer p.leave()
+
 	previousToken := p.previousToken
+	start := p.currentTok.Pos
 
-	var element component
+	var element Node
 
 	switch p.currentTok.Type {
 	case hash:
@@ -103,7 +282,9 @@ func (p *parser) parseComponent(properties []property, closing tokenType, joinPr
 		backslash:
 		element = p.parseParagraph(properties, closing)
 	case backtick:
-		if p.peekTokenIs(backtick) {
+		if fence, ok := p.codeFenceAt(start.Offset); ok {
+			element = p.parseFencedCodeBlock(properties, fence)
+		} else if p.peekTokenIs(backtick) {
 			element = p.parseCodeDouble(properties)
 		} else {
 			element = p.parseCode(properties)
@@ -117,20 +298,20 @@ func (p *parser) parseComponent(properties []property, closing tokenType, joinPr
 	case gt:
 		element, _ = p.parseBlockQuote(properties, closing, 0)
 	case listelement:
-		element = p.parseOrderedListElement(properties, closing)
+		element = p.parseList(properties, closing)
 	case dash:
 		if p.peekTokenIs(space) {
-			element = p.parseUnorderedList(properties, closing)
+			element = p.parseList(properties, closing)
 		} else if p.peekTokenIs(dash) {
 			if previousToken.Type == dash {
-				element = &horizontalRule{Properties: properties}
 				p.nextToken()
 				p.nextToken()
+				element = &HorizontalRule{Properties: properties, SourceRange: p.rangeThrough(start)}
 			} else {
 				p.nextToken()
 				if p.peekTokenIs(dash) {
-					element = &horizontalRule{Properties: properties}
 					p.nextToken()
+					element = &HorizontalRule{Properties: properties, SourceRange: p.rangeThrough(start)}
 				} else {
 					element = p.parseFragment(closing)
 					prefixFragment(element, "-")
@@ -149,8 +330,8 @@ func (p *parser) parseComponent(properties []property, closing tokenType, joinPr
 		if p.peekTokenIs(underscore) {
 			p.nextToken()
 			if p.peekTokenIs(underscore) {
-				element = &horizontalRule{Properties: properties}
 				p.nextToken()
+				element = &HorizontalRule{Properties: properties, SourceRange: p.rangeThrough(start)}
 			} else {
 				element = p.parseFragment(closing)
 				prefixFragment(element, "_")
@@ -167,9 +348,19 @@ func (p *parser) parseComponent(properties []property, closing tokenType, joinPr
 	case lt:
 		element = p.parseShortLink(properties)
 	case tidle:
-		element = p.parseButton(properties)
+		if fence, ok := p.codeFenceAt(start.Offset); ok {
+			element = p.parseFencedCodeBlock(properties, fence)
+		} else {
+			element = p.parseButton(properties)
+		}
 	case at:
 		element = p.parseNav(properties)
+	case pipe:
+		if _, ok := p.tableAlignments(p.currentTok.Pos.Offset); ok {
+			element = p.parseTable(properties)
+		} else {
+			element = p.parseParagraph(properties, closing)
+		}
 	case dollar:
 		element = p.parseSpan(properties, closing)
 	case caret:
@@ -189,6 +380,11 @@ func (p *parser) parseComponent(properties []property, closing tokenType, joinPr
 		}
 	}
 
+	if element != nil {
+		p.countNodes(1)
+		setMissingRanges(element, p.rangeFrom(start))
+	}
+
 	// if block component, skip newlines
 	if element != nil && isBlockElement(element) {
 		for p.peekTokenIs(newline) {
@@ -200,125 +396,281 @@ func (p *parser) parseComponent(properties []property, closing tokenType, joinPr
 		// if line starts with an inline element and is followed by a paragraph, wrap the first inline element
 		// in following paragraph
 		if joinsParagraph(element) && !p.curTokenIs(newline) && p.peekTokenIs(word) && previousToken.Type == newline {
-			pComponent := p.parseParagraph(nil, closing).(*paragraph)
-			paragraphChildren := append([]component{element}, pComponent.Content...)
-			pComponent.Content = paragraphChildren
-			element = pComponent
+			// the paragraph is nil when the input ends before any of its content
+			if pComponent, ok := p.parseParagraph(nil, closing).(*Paragraph); ok {
+				paragraphChildren := append([]Node{element}, pComponent.Content...)
+				pComponent.Content = paragraphChildren
+				pComponent.SourceRange = p.rangeFrom(start)
+				element = pComponent
+				p.countNodes(1)
+			}
 		}
 	}
 
 	return element
 }
 
-func joinsParagraph(comp component) bool {
+// Sets the range of node, and any of its descendants, that weren't given a range while parsing.
+// Descendants without a range inherit the range of their closest parent that has one.
+func setMissingRanges(node Node, sourceRange SourceRange) {
+	parentRanges := []SourceRange{sourceRange}
+	Walk(node, VisitorFunc(func(n Node, entering bool) (Node, WalkStatus) {
+		if !entering {
+			parentRanges = parentRanges[:len(parentRanges)-1]
+			return n, WalkContinue
+		}
+
+		if n.Range() != (SourceRange{}) {
+			// descendants of a node given a range while parsing were ranged as they were parsed,
+			// so walking them again would only make deeply nested input slow
+			parentRanges = append(parentRanges, n.Range())
+			return n, WalkSkipChildren
+		}
+
+		if r, ok := n.(interface{ setRange(SourceRange) }); ok {
+			r.setRange(parentRanges[len(parentRanges)-1])
+		}
+
+		parentRanges = append(parentRanges, n.Range())
+		return n, WalkContinue
+	}))
+}
+
+func joinsParagraph(comp Node) bool {
 	switch comp.(type) {
-	case *code,
-		*bold,
-		*italic,
-		*span,
-		*button,
-		*link:
+	case *Code,
+		*Bold,
+		*Italic,
+		*Span,
+		*Button,
+		*Link:
 		return true
 	}
 	return false
 }
 
-func isBlockElement(comp component) bool {
+func isBlockElement(comp Node) bool {
 	switch comp.(type) {
-	case *div,
-		*codeBlock,
-		*horizontalRule,
-		*image,
-		*nav:
+	case *Div,
+		*CodeBlock,
+		*HorizontalRule,
+		*Image,
+		*Nav:
 		return true
 	}
 	return false
 }
 
-func (p *parser) parseProperties() ([]property, error, string) {
-	props := make([]property, 0)
+func (p *parser) parseProperties() ([]Property, error, string) {
+	props, err, propsString := p.parsePropertyList()
+	if err != nil {
+		return props, err, propsString
+	}
+
+	p.nextToken()
+	for p.curTokenIs(space) || p.curTokenIs(newline) {
+		p.nextToken()
+	}
+
+	return props, nil, ""
+}
+
+// Parses properties within a line of text, which only apply to an element directly after them, as in
+// "{ .class=x } **bold**". Returns false when they are malformed or followed by anything else, such as the
+// "{{ .Content }}" of a template, leaving the parser on their last token and returning their text to be kept as written.
+func (p *parser) parseInlineProperties() ([]Property, bool, string) {
+	warningCount := len(p.warnings)
+	properties, err, propsText := p.parsePropertyList()
+	if err != nil {
+		return nil, false, propsText
+	}
+
+	rest := strings.TrimLeft(p.lex.input[p.currentTok.End.Offset:], " \t\r\n")
+	if next := newLexer(rest).nextToken(); !(next.IsElementToken() || next.Type == lt) {
+		p.warnings = p.warnings[:warningCount]
+		return nil, false, propsText
+	}
+
+	p.nextToken()
+	for p.curTokenIs(space) || p.curTokenIs(newline) || p.curTokenIs(tab) {
+		p.nextToken()
+	}
+	return properties, true, ""
+}
+
+// Parses properties like parseProperties, but stops on the closing } instead of moving past it and the whitespace
+// after it.
+func (p *parser) parsePropertyList() ([]Property, error, string) {
+	props := make([]Property, 0)
 	propsString := "{"
+	opening := p.currentTok
 	for !p.curTokenIs(rsquirly) {
-		if p.curTokenIs(dot) {
+		if p.curTokenIs(eof) {
+			return nil, p.errorAt(opening, "Property formatted incorrectly. LSQUIRLY is missing a closing RSQUIRLY"), propsString
+		}
+
+		if p.curTokenIs(hash) {
 			if !p.peekTokenIs(word) {
-				errorMessage := "Property formatted incorrectly. DOT must be followed by a WORD"
-				return nil, &parseError{errorReason: errorMessage}, propsString
+				errorMessage := "Property formatted incorrectly. HASH must be followed by a WORD"
+				return nil, p.errorAt(p.nextTok, errorMessage), propsString
 			}
 
+			// #intro or #intro.card.highlight
 			p.nextToken()
 			propsString += p.currentTok.Literal
-			key := p.currentTok.Literal
-
-			if !p.peekTokenIs(equals) {
-				errorMessage := "Property formatted incorrectly. KEY must be follwed by EQUALS"
-				return nil, &parseError{errorReason: errorMessage}, propsString
+			id, classes, _ := strings.Cut(p.currentTok.Literal, ".")
+			props = append(props, Property{Name: "id", Value: id})
+			props = addClasses(props, classes)
+		} else if p.curTokenIs(dot) {
+			if !p.peekTokenIs(word) {
+				errorMessage := "Property formatted incorrectly. DOT must be followed by a WORD"
+				return nil, p.errorAt(p.nextTok, errorMessage), propsString
 			}
 
 			p.nextToken()
 			propsString += p.currentTok.Literal
-			if !p.peekTokenIs(word) {
-				errorMessage := "Property formatted incorrectly. EQUALS must be followed by VALUE"
-				return nil, &parseError{errorReason: errorMessage}, propsString
-			}
+			key := p.currentTok.Literal
 
-			p.nextToken()
-			value := p.currentTok.Literal
-			props = append(props, property{Name: key, Value: value})
+			if !p.peekTokenIs(equals) && key != "class" && key != "id" && isPropertySeparator(p.nextTok) {
+				if strings.Contains(key, ".") {
+					// .card.highlight
+					props = addClasses(props, key)
+				} else {
+					// boolean attribute such as .hidden
+					props = append(props, Property{Name: key})
+				}
+			} else {
+				if !p.peekTokenIs(equals) {
+					errorMessage := "Property formatted incorrectly. KEY must be followed by EQUALS"
+					return nil, p.errorAt(p.nextTok, errorMessage), propsString
+				}
+
+				p.nextToken()
+				propsString += p.currentTok.Literal
+				if !p.peekTokenIs(word) && !p.peekTokenIs(quoted) {
+					errorMessage := "Property formatted incorrectly. EQUALS must be followed by VALUE"
+					return nil, p.errorAt(p.nextTok, errorMessage), propsString
+				}
+
+				p.nextToken()
+				propsString += p.currentTok.Literal
+				value := p.currentTok.Literal
+				if p.curTokenIs(quoted) {
+					var terminated bool
+					if value, terminated = unquote(value); !terminated {
+						errorMessage := "Property formatted incorrectly. QUOTE is missing a closing QUOTE"
+						return nil, p.errorAt(p.currentTok, errorMessage), propsString
+					}
+				}
+				props = append(props, Property{Name: key, Value: value})
+			}
 		}
 
 		p.nextToken()
 		propsString += p.currentTok.Literal
 	}
 
-	p.nextToken()
-	for p.curTokenIs(space) || p.curTokenIs(newline) {
-		p.nextToken()
+	// merge here too, so that the document holds the properties as they will be rendered
+	props, messages := MergeProperties(props)
+	for _, message := range messages {
+		p.warnings = append(p.warnings, Warning{Filename: p.filename, Pos: opening.Pos, Message: message})
 	}
 
-	return props, nil, ""
+	return props, nil, propsString
+}
+
+// Reports whether tok ends a property written without a value.
+func isPropertySeparator(tok token) bool {
+	return tok.Type == space || tok.Type == tab || tok.Type == newline || tok.Type == rsquirly
+}
+
+// Adds each of the dot separated classes, such as card.highlight, to props.
+func addClasses(props []Property, classes string) []Property {
+	for _, class := range strings.Split(classes, ".") {
+		if len(class) > 0 {
+			props = append(props, Property{Name: "class", Value: class})
+		}
+	}
+	return props
 }
 
-func (p *parser) parseFragment(closing tokenType) *fragment {
+// Returns the value inside a quoted token with its escapes resolved, and whether the token has a closing quote.
+func unquote(literal string) (string, bool) {
+	quote := literal[0]
+	var sb strings.Builder
+	for i := 1; i < len(literal); i++ {
+		switch ch := literal[i]; {
+		case ch == '\\' && i+1 < len(literal):
+			i++
+			sb.WriteByte(literal[i])
+		case ch == quote:
+			return sb.String(), true
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseFragment(closing tokenType) *Fragment {
 	content := p.parseTextLine(closing)
-	return &fragment{Value: content}
+	return &Fragment{Value: content}
 }
 
 func (p *parser) parseTextLine(closing tokenType) string {
 	var lineString string
-	for !(p.curTokenIs(newline) || p.curTokenIs(closing)) {
+	for !(p.curTokenIs(newline) || p.curTokenIs(closing) || p.curTokenIs(eof)) {
 		lineString += p.currentTok.Literal
 		p.nextToken()
 	}
 	return lineString
 }
 
-// Appends a fragment containing fragmentValue to the lineElements slice after replacing '\\n' with spaces.
-// Subsequently sets fragmentValue to an empty string.
-func bankCurrentFragment(lineElements *[]component, fragmentValue *string) {
-	if len(*fragmentValue) > 0 {
-		*lineElements = append(*lineElements, &fragment{Value: strings.ReplaceAll(*fragmentValue, "\\n", " ")})
-		*fragmentValue = ""
+// Plain text collected between inline elements, along with where it was found in the source.
+type textBuffer struct {
+	value string
+	SourceRange
+}
+
+// Appends text to the buffer, extending its range to cover tok.
+func (b *textBuffer) add(text string, tok token) {
+	if len(b.value) == 0 {
+		b.Start = tok.Pos
 	}
+	b.value += text
+	b.End = tok.End
 }
 
-func (p *parser) parseLine(closing tokenType) []component {
-	lineElements := make([]component, 0)
-	var lineString string
+// Appends a fragment containing the buffered text to the lineElements slice after replacing '\\n' with spaces.
+// Subsequently empties the buffer.
+func (p *parser) bankCurrentFragment(lineElements *[]Node, buffer *textBuffer) {
+	if len(buffer.value) > 0 {
+		p.countNodes(1)
+		fragment := &Fragment{Value: strings.ReplaceAll(buffer.value, "\\n", " "), SourceRange: buffer.SourceRange}
+		*lineElements = append(*lineElements, fragment)
+		buffer.value = ""
+	}
+}
+
+func (p *parser) parseLine(closing tokenType) []Node {
+	lineElements := make([]Node, 0)
+	var lineString textBuffer
 
-	for !(p.curTokenIs(newline) || p.curTokenIs(closing)) {
+	for !(p.curTokenIs(newline) || p.curTokenIs(closing) || p.curTokenIs(eof)) {
 		if p.currentTok.IsInlineElement() {
-			bankCurrentFragment(&lineElements, &lineString)
-			lineElements = append(lineElements, p.parseComponent(nil, closing, false))
+			p.bankCurrentFragment(&lineElements, &lineString)
+			if component := p.parseComponent(nil, closing, false); component != nil {
+				lineElements = append(lineElements, component)
+			}
 		} else if p.curTokenIs(lsquirly) {
-			properties, parseErr, propsText := p.parseProperties()
-			if parseErr != nil {
-				lineString += propsText
+			propsStart := p.currentTok
+			properties, ok, propsText := p.parseInlineProperties()
+			if !ok {
+				lineString.add(propsText, propsStart)
+				lineString.End = p.currentTok.End
 				p.nextToken()
 			} else {
-				for p.curTokenIs(tab) {
-					p.nextToken()
-				}
-				bankCurrentFragment(&lineElements, &lineString)
+				p.bankCurrentFragment(&lineElements, &lineString)
 				nextComponent := p.parseComponent(properties, closing, false)
 				if nextComponent != nil {
 					lineElements = append(lineElements, nextComponent)
@@ -330,34 +682,35 @@ func (p *parser) parseLine(closing tokenType) []component {
 			}
 
 			if !(p.currentTok.Type == space && p.peekTokenIs(closing)) {
-				lineString += p.currentTok.Literal
+				lineString.add(p.currentTok.Literal, p.currentTok)
 			}
 			p.nextToken()
 		}
 	}
 
-	bankCurrentFragment(&lineElements, &lineString)
+	p.bankCurrentFragment(&lineElements, &lineString)
 	return lineElements
 }
 
-func (p *parser) parseBlockQuoteLine(closing tokenType) []component {
-	lineElements := make([]component, 0)
-	var lineString string
+func (p *parser) parseBlockQuoteLine(closing tokenType) []Node {
+	lineElements := make([]Node, 0)
+	var lineString textBuffer
 
-	for !(p.curTokenIs(newline) || p.curTokenIs(closing)) {
+	for !(p.curTokenIs(newline) || p.curTokenIs(closing) || p.curTokenIs(eof)) {
 		if p.currentTok.IsElementToken() {
-			bankCurrentFragment(&lineElements, &lineString)
-			lineElements = append(lineElements, p.parseComponent(nil, closing, false))
+			p.bankCurrentFragment(&lineElements, &lineString)
+			if component := p.parseComponent(nil, closing, false); component != nil {
+				lineElements = append(lineElements, component)
+			}
 		} else if p.curTokenIs(lsquirly) {
-			properties, parseErr, propsText := p.parseProperties()
-			if parseErr != nil {
-				lineString += propsText
+			propsStart := p.currentTok
+			properties, ok, propsText := p.parseInlineProperties()
+			if !ok {
+				lineString.add(propsText, propsStart)
+				lineString.End = p.currentTok.End
 				p.nextToken()
 			} else {
-				for p.curTokenIs(tab) {
-					p.nextToken()
-				}
-				bankCurrentFragment(&lineElements, &lineString)
+				p.bankCurrentFragment(&lineElements, &lineString)
 				nextComponent := p.parseComponent(properties, closing, false)
 				if nextComponent != nil {
 					lineElements = append(lineElements, nextComponent)
@@ -369,7 +722,7 @@ func (p *parser) parseBlockQuoteLine(closing tokenType) []component {
 			}
 
 			if !(p.currentTok.Type == space && p.peekTokenIs(closing)) {
-				lineString += p.currentTok.Literal
+				lineString.add(p.currentTok.Literal, p.currentTok)
 			}
 			p.nextToken()
 		}
@@ -382,28 +735,29 @@ func (p *parser) parseBlockQuoteLine(closing tokenType) []component {
 		}
 	}
 
-	bankCurrentFragment(&lineElements, &lineString)
+	p.bankCurrentFragment(&lineElements, &lineString)
 	return lineElements
 }
 
-func (p *parser) parseLineDoubleClose(closing tokenType) []component {
-	lineElements := make([]component, 0)
-	var lineString string
+func (p *parser) parseLineDoubleClose(closing tokenType) []Node {
+	lineElements := make([]Node, 0)
+	var lineString textBuffer
 
-	for !(p.curTokenIs(newline) || (p.curTokenIs(closing) && p.peekTokenIs(closing))) {
+	for !(p.curTokenIs(newline) || p.curTokenIs(eof) || (p.curTokenIs(closing) && p.peekTokenIs(closing))) {
 		if p.currentTok.IsInlineElement() {
-			bankCurrentFragment(&lineElements, &lineString)
-			lineElements = append(lineElements, p.parseComponent(nil, closing, false))
+			p.bankCurrentFragment(&lineElements, &lineString)
+			if component := p.parseComponent(nil, closing, false); component != nil {
+				lineElements = append(lineElements, component)
+			}
 		} else if p.curTokenIs(lsquirly) {
-			properties, parseErr, propsText := p.parseProperties()
-			if parseErr != nil {
-				lineString += propsText
+			propsStart := p.currentTok
+			properties, ok, propsText := p.parseInlineProperties()
+			if !ok {
+				lineString.add(propsText, propsStart)
+				lineString.End = p.currentTok.End
 				p.nextToken()
 			} else {
-				for p.curTokenIs(tab) {
-					p.nextToken()
-				}
-				bankCurrentFragment(&lineElements, &lineString)
+				p.bankCurrentFragment(&lineElements, &lineString)
 				nextComponent := p.parseComponent(properties, closing, false)
 				if nextComponent != nil {
 					lineElements = append(lineElements, nextComponent)
@@ -414,7 +768,7 @@ func (p *parser) parseLineDoubleClose(closing tokenType) []component {
 				p.nextToken()
 			}
 
-			lineString += p.currentTok.Literal
+			lineString.add(p.currentTok.Literal, p.currentTok)
 			p.nextToken()
 		}
 
@@ -426,28 +780,29 @@ func (p *parser) parseLineDoubleClose(closing tokenType) []component {
 		}
 	}
 
-	bankCurrentFragment(&lineElements, &lineString)
+	p.bankCurrentFragment(&lineElements, &lineString)
 	return lineElements
 }
 
-func (p *parser) parseBlock(closing tokenType) []component {
-	blockElements := make([]component, 0)
-	var blockString string
+func (p *parser) parseBlock(closing tokenType) []Node {
+	blockElements := make([]Node, 0)
+	var blockString textBuffer
 
-	for !(p.curTokenIs(eof) || p.curTokenIs(closing) || p.isDoubleBreak() || p.isAfterNewline(closing) || p.isNextLineBlockElement()) {
+	for !(p.curTokenIs(eof) || p.curTokenIs(closing) || p.isDoubleBreak() || p.isAfterNewline(closing) || p.isNextLineBlockElement() || p.isNextLineListItem()) {
 		if p.currentTok.IsInlineElement() {
-			bankCurrentFragment(&blockElements, &blockString)
-			blockElements = append(blockElements, p.parseComponent(nil, closing, true))
+			p.bankCurrentFragment(&blockElements, &blockString)
+			if component := p.parseComponent(nil, closing, true); component != nil {
+				blockElements = append(blockElements, component)
+			}
 		} else if p.curTokenIs(lsquirly) {
-			properties, parseErr, propsText := p.parseProperties()
-			if parseErr != nil {
-				blockString += propsText
+			propsStart := p.currentTok
+			properties, ok, propsText := p.parseInlineProperties()
+			if !ok {
+				blockString.add(propsText, propsStart)
+				blockString.End = p.currentTok.End
 				p.nextToken()
 			} else {
-				for p.curTokenIs(tab) {
-					p.nextToken()
-				}
-				bankCurrentFragment(&blockElements, &blockString)
+				p.bankCurrentFragment(&blockElements, &blockString)
 				nextComponent := p.parseComponent(properties, closing, true)
 				if nextComponent != nil {
 					blockElements = append(blockElements, nextComponent)
@@ -459,13 +814,13 @@ func (p *parser) parseBlock(closing tokenType) []component {
 			}
 
 			if !(p.currentTok.Type == space && p.peekTokenIs(closing)) {
-				blockString += p.currentTok.Literal
+				blockString.add(p.currentTok.Literal, p.currentTok)
 			}
 			p.nextToken()
 		}
 
-		if p.curTokenIs(newline) && (p.peekTokenIs(tab) || p.peekTokenIs(space)) {
-			blockString += " "
+		if p.curTokenIs(newline) && (p.peekTokenIs(tab) || p.peekTokenIs(space)) && !p.isNextLineListItem() && !p.isNextLineBlockElement() {
+			blockString.add(" ", p.currentTok)
 			p.nextToken()
 			for p.curTokenIs(tab) || p.curTokenIs(space) {
 				p.nextToken()
@@ -473,11 +828,11 @@ func (p *parser) parseBlock(closing tokenType) []component {
 		}
 	}
 
-	bankCurrentFragment(&blockElements, &blockString)
+	p.bankCurrentFragment(&blockElements, &blockString)
 	return blockElements
 }
 
-func (p *parser) parseHeader(props []property, closing tokenType) component {
+func (p *parser) parseHeader(props []Property, closing tokenType) Node {
 	level := 0
 	for p.curTokenIs(hash) {
 		level++
@@ -490,48 +845,66 @@ func (p *parser) parseHeader(props []property, closing tokenType) component {
 	}
 
 	p.nextToken()
+
+	// properties can also follow the hashes, as in "# { .class=title } Heading"
+	if p.curTokenIs(lsquirly) {
+		opening := p.currentTok
+		headingProps, err, _ := p.parsePropertyList()
+		if err != nil {
+			p.fail(err)
+			return nil
+		}
+
+		var messages []string
+		props, messages = MergeProperties(slices.Concat(props, headingProps))
+		for _, message := range messages {
+			p.warnings = append(p.warnings, Warning{Filename: p.filename, Pos: opening.Pos, Message: message})
+		}
+
+		p.nextToken()
+		for p.curTokenIs(space) || p.curTokenIs(tab) {
+			p.nextToken()
+		}
+	}
+
 	contentElements := p.parseLine(closing)
-	return &header{Level: level, Content: contentElements, Properties: props}
+	return &Heading{Level: level, Content: contentElements, Properties: props}
 }
 
-func (p *parser) parseParagraph(props []property, closing tokenType) component {
+func (p *parser) parseParagraph(props []Property, closing tokenType) Node {
 	// content := strings.ReplaceAll(p.parseTextBlock(closing), "\\n", " ")
 	contentElements := p.parseBlock(closing)
 	if len(contentElements) == 0 {
 		return nil
 	}
 
-	return &paragraph{Content: contentElements, Properties: props}
+	return &Paragraph{Content: contentElements, Properties: props}
 }
 
-func prefixFragment(comp component, prefix string) {
+func prefixFragment(comp Node, prefix string) {
 	switch c := (comp).(type) {
-	case *fragment:
+	case *Fragment:
 		c.Value = prefix + c.Value
 	}
 }
 
-func (p *parser) parseCode(properties []property) component {
+func (p *parser) parseCode(properties []Property) Node {
 	p.nextToken()
 	var codeString string
 
 	for !p.curTokenIs(backtick) {
 		if p.curTokenIs(newline) || p.curTokenIs(eof) {
-			return &paragraph{Properties: properties, Content: []component{&fragment{Value: "`" + codeString}}}
+			return &Paragraph{Properties: properties, Content: []Node{&Fragment{Value: "`" + codeString}}}
 		}
 		codeString += p.currentTok.Literal
 		p.nextToken()
 	}
 
-	// handle edge case where html tags are enclosed in code element
-	// using encoding for left angle bracket prevents contents being parsed as actual html
-	codeString = strings.ReplaceAll(codeString, "<", "&lt;")
-
 	p.nextToken()
-	return &code{Properties: properties, Text: codeString}
+	return &Code{Properties: properties, Text: codeString}
 }
 
-func (p *parser) parseCodeDouble(properties []property) component {
+func (p *parser) parseCodeDouble(properties []Property) Node {
 	p.nextToken()
 	p.nextToken()
 
@@ -544,14 +917,14 @@ func (p *parser) parseCodeDouble(properties []property) component {
 	p.nextToken()
 	p.nextToken()
 
-	return &code{Properties: properties, Text: codeText}
+	return &Code{Properties: properties, Text: codeText}
 }
 
-func (p *parser) parseStrong(properties []property, closing tokenType) component {
+func (p *parser) parseStrong(properties []Property, closing tokenType) Node {
 	p.nextToken()
 	if p.peekTokenIs(space) || p.peekTokenIs(newline) || p.peekTokenIs(eof) {
 		content := p.parseTextLine(closing)
-		return &paragraph{Properties: properties, Content: []component{&fragment{Value: "*" + content}}}
+		return &Paragraph{Properties: properties, Content: []Node{&Fragment{Value: "*" + content}}}
 	}
 
 	p.nextToken()
@@ -561,25 +934,36 @@ func (p *parser) parseStrong(properties []property, closing tokenType) component
 	p.nextToken()
 	p.nextToken()
 
-	return &bold{Properties: properties, Content: content}
+	return &Bold{Properties: properties, Content: content}
 }
 
-func (p *parser) parseEm(properties []property, closing tokenType) component {
+func (p *parser) parseEm(properties []Property, closing tokenType) Node {
 	if p.peekTokenIs(space) || p.peekTokenIs(newline) || p.peekTokenIs(eof) {
 		content := p.parseTextLine(closing)
 		p.nextToken()
-		return &paragraph{Properties: properties, Content: []component{&fragment{Value: content}}}
+		return &Paragraph{Properties: properties, Content: []Node{&Fragment{Value: content}}}
 	}
 
 	p.nextToken()
 	content := p.parseLine(asterisk)
 
 	p.nextToken()
-	return &italic{Properties: properties, Content: content}
+	return &Italic{Properties: properties, Content: content}
 }
 
-func (p *parser) parseBlockQuote(properties []property, closing tokenType, initialDepth int) (component, int) {
-	content := make([]component, 0)
+func (p *parser) parseBlockQuote(properties []Property, closing tokenType, initialDepth int) (Node, int) {
+	if initialDepth > 0 {
+		// nested quotes are parsed recursively without going through parseComponent
+		if !p.enter() {
+			return &BlockQuote{Properties: properties}, 0
+		}
+		defer p.leave()
+		p.countNodes(1)
+	}
+
+	// the range ends with the last quoted line, not the markers of the line that follows
+	sourceRange := p.rangeThrough(p.currentTok.Pos)
+	content := make([]Node, 0)
 	depth := initialDepth
 
 	for p.curTokenIs(gt) {
@@ -587,6 +971,7 @@ func (p *parser) parseBlockQuote(properties []property, closing tokenType, initi
 		if depth > initialDepth+1 {
 			nested, _ := p.parseBlockQuote(properties, closing, depth-1)
 			content = append(content, nested)
+			sourceRange.End = nested.Range().End
 			depth = initialDepth
 		} else {
 			p.nextToken()
@@ -600,6 +985,10 @@ func (p *parser) parseBlockQuote(properties []property, closing tokenType, initi
 	for !(p.curTokenIs(newline) || p.curTokenIs(eof)) {
 		lineContent := p.parseBlockQuoteLine(closing)
 		content = append(content, lineContent...)
+		sourceRange.End = p.contentEnd
+		if p.isNextLineListItem() {
+			break
+		}
 		p.nextToken()
 
 		if p.curTokenIs(newline) || p.curTokenIs(eof) || p.curTokenIs(closing) {
@@ -618,11 +1007,11 @@ func (p *parser) parseBlockQuote(properties []property, closing tokenType, initi
 
 		if nextDepth < depth && p.curTokenIs(newline) {
 			p.nextToken()
-			return &blockQuote{Properties: properties, Content: content}, nextDepth
+			return &BlockQuote{Properties: properties, Content: content, SourceRange: sourceRange}, nextDepth
 		}
 
 		if nextDepth == depth && p.curTokenIs(newline) {
-			content = append(content, &lineBreak{})
+			content = append(content, &LineBreak{SourceRange: p.rangeThrough(p.currentTok.Pos)})
 			p.nextToken()
 			for p.curTokenIs(gt) {
 				p.nextToken()
@@ -636,8 +1025,9 @@ func (p *parser) parseBlockQuote(properties []property, closing tokenType, initi
 		if nextDepth > depth {
 			nested, d := p.parseBlockQuote(properties, closing, nextDepth)
 			content = append(content, nested)
+			sourceRange.End = nested.Range().End
 			if d < depth {
-				return &blockQuote{Properties: properties, Content: content}, d
+				return &BlockQuote{Properties: properties, Content: content, SourceRange: sourceRange}, d
 			}
 
 			nextDepth = 0
@@ -652,57 +1042,292 @@ func (p *parser) parseBlockQuote(properties []property, closing tokenType, initi
 				if nextDepth != 0 && p.curTokenIs(newline) {
 					p.nextToken()
 				}
-				return &blockQuote{Properties: properties, Content: content}, nextDepth
+				return &BlockQuote{Properties: properties, Content: content, SourceRange: sourceRange}, nextDepth
 			}
 		} else {
-			content = append(content, &fragment{Value: " "})
+			// lines joined into one are separated where the previous line ended
+			content = append(content, &Fragment{Value: " ", SourceRange: SourceRange{Start: sourceRange.End, End: sourceRange.End}})
 		}
 	}
 
-	return &blockQuote{Properties: properties, Content: content}, 0
+	return &BlockQuote{Properties: properties, Content: content, SourceRange: sourceRange}, 0
 }
 
-func (p *parser) parseOrderedListElement(properties []property, closing tokenType) component {
-	start, parseErr := strconv.Atoi(strings.TrimSuffix(p.currentTok.Literal, "."))
+// Parses a list starting at the current marker. Lines indented further than the marker belong to the item above them,
+// holding more paragraphs, code blocks, quotes or nested lists. The list continues while following lines start with
+// the same kind of marker at the same indentation, even after blank lines.
+func (p *parser) parseList(properties []Property, closing tokenType) Node {
+	start := p.currentTok.Pos
+	ordered := p.curTokenIs(listelement)
+	firstNumber, parseErr := strconv.Atoi(strings.TrimSuffix(p.currentTok.Literal, "."))
 	if parseErr != nil {
-		start = 1
+		firstNumber = 1
 	}
 
-	listElements := make([]listItem, 0)
-	for !(p.curTokenIs(eof) || (p.curTokenIs(newline) && !p.peekTokenIs(listelement))) {
+	lineStart := strings.LastIndexAny(p.lex.input[:start.Offset], "\r\n") + 1
+	indent, _ := indentationAt(p.lex.input, lineStart)
+
+	listElements := make([]ListItem, 0)
+	for p.err == nil {
+		itemStart := p.currentTok.Pos
 		p.nextToken()
-		if p.curTokenIs(listelement) {
-			p.nextToken()
+		item := p.parseListItemLine(itemStart, !ordered, closing)
+
+		for p.err == nil && p.curTokenIs(newline) {
+			contentIndent, contentStart := indentationAt(p.lex.input, skipBlankLines(p.lex.input, p.nextTok.Pos.Offset))
+			if contentStart >= len(p.lex.input) || contentIndent <= indent {
+				break
+			}
+
+			item.Children = append(item.Children, p.parseListItemBlocks(p.skipToNextContent(), closing)...)
+			item.End = p.contentEnd
+		}
+		listElements = append(listElements, item)
+
+		marker, ok := p.peekListMarker()
+		if !ok || marker.indent != indent || marker.ordered != ordered || marker.hasProperties {
+			break
 		}
-		elementContent := strings.TrimSpace(p.parseTextLine(closing))
-		element := listItem{Component: &paragraph{Content: []component{&fragment{Value: elementContent}}}}
-		listElements = append(listElements, element)
+		p.skipToNextContent()
+	}
+
+	sourceRange := p.rangeFrom(start)
+	if ordered {
+		return &OrderedList{Properties: properties, ListItems: listElements, Start: firstNumber, SourceRange: sourceRange}
 	}
+	return &UnorderedList{Properties: properties, ListItems: listElements, SourceRange: sourceRange}
+}
 
-	return &orderedList{Properties: properties, ListItems: listElements, Start: start}
+// Returns the list marker starting the next line that isn't blank, if the current token ends a line.
+func (p *parser) peekListMarker() (listMarker, bool) {
+	if p.err != nil || !p.curTokenIs(newline) {
+		return listMarker{}, false
+	}
+	return listMarkerAt(p.lex.input, skipBlankLines(p.lex.input, p.nextTok.Pos.Offset))
 }
 
-func (p *parser) parseUnorderedList(properties []property, closing tokenType) component {
-	listElements := make([]listItem, 0)
-	for !(p.curTokenIs(eof) || (p.curTokenIs(newline) && !p.peekTokenIs(dash))) {
+// Parses the components on a line indented under a list item, which may continue over the following lines.
+func (p *parser) parseListItemBlocks(properties []Property, closing tokenType) []Node {
+	blocks := make([]Node, 0)
+	for !(p.curTokenIs(newline) || p.curTokenIs(closing) || p.curTokenIs(eof)) && p.err == nil {
+		if component := p.parseComponent(properties, closing, false); component != nil {
+			blocks = append(blocks, component)
+			properties = nil
+		}
+
+		if p.curTokenIs(newline) || p.curTokenIs(closing) {
+			break
+		}
 		p.nextToken()
-		if p.curTokenIs(dash) {
-			if !p.peekTokenIs(space) {
-				return &unorderedList{Properties: properties, ListItems: listElements}
-			}
+	}
+	return blocks
+}
 
-			p.nextToken()
+// Moves from the end of a line past any blank lines and indentation to the next content, returning the properties
+// written before it.
+func (p *parser) skipToNextContent() []Property {
+	for p.curTokenIs(space) || p.curTokenIs(tab) || p.curTokenIs(newline) {
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(lsquirly) {
+		return nil
+	}
+
+	properties, err, _ := p.parseProperties()
+	if err != nil {
+		p.fail(err)
+		return nil
+	}
+
+	for p.curTokenIs(space) || p.curTokenIs(tab) || p.curTokenIs(newline) {
+		p.nextToken()
+	}
+	return properties
+}
+
+// A list marker found by looking ahead in the source.
+type listMarker struct {
+	// width of the whitespace before the marker, with tabs advancing to the next multiple of tabWidth
+	indent  int
+	ordered bool
+	// whether the marker is preceded by a line of properties
+	hasProperties bool
+}
+
+const tabWidth = 4
+
+// Returns the list marker at the start of the line beginning at offset in input. A line holding only properties may
+// come before the marker, which then gives the indentation of the line it is on.
+func listMarkerAt(input string, offset int) (listMarker, bool) {
+	var marker listMarker
+	indent, i := indentationAt(input, offset)
+
+	if i < len(input) && input[i] == '{' {
+		i = propertiesEnd(input, i)
+		if i < 0 {
+			return marker, false
+		}
+
+		for i < len(input) && (input[i] == ' ' || input[i] == '\t') {
+			i++
+		}
+		if i >= len(input) || (input[i] != '\n' && input[i] != '\r') {
+			return marker, false
+		}
+
+		// the next line that isn't blank must be the marker
+		indent, i = indentationAt(input, skipBlankLines(input, i))
+		marker.hasProperties = true
+	}
+
+	marker.indent = indent
+	rest := input[i:]
+	if strings.HasPrefix(rest, "- ") {
+		return marker, true
+	}
+
+	digits := len(rest) - len(strings.TrimLeft(rest, "0123456789"))
+	if digits > 0 && strings.HasPrefix(rest[digits:], ".") {
+		marker.ordered = true
+		return marker, true
+	}
+	return marker, false
+}
+
+// Returns the offset of the start of the first line from offset in input that isn't blank, or the end of the input.
+func skipBlankLines(input string, offset int) int {
+	lineStart := offset
+	for i := offset; i < len(input); i++ {
+		switch input[i] {
+		case ' ', '\t':
+		case '\n', '\r':
+			lineStart = i + 1
+		default:
+			return lineStart
+		}
+	}
+	return len(input)
+}
+
+// Returns the width of the spaces and tabs starting at offset in input, and the offset of the first other character.
+func indentationAt(input string, offset int) (int, int) {
+	indent := 0
+	for ; offset < len(input); offset++ {
+		switch input[offset] {
+		case ' ':
+			indent++
+		case '\t':
+			indent += tabWidth - indent%tabWidth
+		default:
+			return indent, offset
+		}
+	}
+	return indent, offset
+}
+
+// Returns the offset just past the } closing the properties that open at offset in input, or -1 if they aren't closed
+// on the same line. Braces in quoted values are skipped, as they are by the lexer.
+func propertiesEnd(input string, offset int) int {
+	for i := offset + 1; i < len(input) && !isLineEnd(rune(input[i])); i++ {
+		switch ch := input[i]; {
+		case ch == '}':
+			return i + 1
+		case isQuote(rune(ch)) && input[i-1] == '=':
+			for i++; i < len(input) && input[i] != ch && !isLineEnd(rune(input[i])); i++ {
+				if input[i] == '\\' && i+1 < len(input) && !isLineEnd(rune(input[i+1])) {
+					i++
+				}
+			}
 		}
+	}
+	return -1
+}
+
+// Parses the rest of the line following a list marker into a list item starting at the marker.
+func (p *parser) parseListItemLine(itemStart Position, allowTask bool, closing tokenType) ListItem {
+	for p.curTokenIs(space) || p.curTokenIs(tab) {
+		p.nextToken()
+	}
+
+	var task ListItem
+	if allowTask {
+		p.parseTaskBox(&task)
+	}
+
+	contentRange := p.rangeFrom(p.currentTok.Pos)
+	content := trimTrailingSpace(p.parseLine(closing))
+	contentRange.End = p.contentEnd
+	if len(content) == 0 {
+		p.countNodes(1)
+		content = append(content, &Fragment{SourceRange: contentRange})
+	}
+
+	p.countNodes(2)
+	paragraph := &Paragraph{Content: content, SourceRange: contentRange}
+	task.Component = paragraph
+	task.SourceRange = SourceRange{Start: itemStart, End: contentRange.End}
+	return task
+}
+
+// Parses the [ ] or [x] checkbox starting a task list item into item, along with any properties written after it.
+// Does nothing when the item doesn't start with a checkbox.
+func (p *parser) parseTaskBox(item *ListItem) {
+	boxStart := p.currentTok.Pos.Offset
+	boxEnd := boxStart + len("[ ]")
+	if boxEnd > len(p.lex.input) {
+		return
+	}
+
+	box := p.lex.input[boxStart:boxEnd]
+	if box != "[ ]" && box != "[x]" && box != "[X]" {
+		return
+	}
+	if boxEnd < len(p.lex.input) && !strings.ContainsRune(" \t\r\n", rune(p.lex.input[boxEnd])) {
+		return
+	}
+
+	item.Task = true
+	item.Checked = box != "[ ]"
+	for p.currentTok.Pos.Offset < boxEnd && !p.curTokenIs(eof) {
+		p.nextToken()
+	}
+	for p.curTokenIs(space) || p.curTokenIs(tab) {
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(lsquirly) {
+		return
+	}
+
+	properties, err, _ := p.parseProperties()
+	if err != nil {
+		p.fail(err)
+		return
+	}
+	item.CheckboxProperties = properties
+	for p.curTokenIs(space) || p.curTokenIs(tab) {
+		p.nextToken()
+	}
+}
 
-		elementContent := strings.TrimSpace(p.parseTextLine(closing))
-		element := listItem{Component: &paragraph{Content: []component{&fragment{Value: elementContent}}}}
-		listElements = append(listElements, element)
+// Removes whitespace from the end of the last fragment in nodes, dropping the fragment if nothing is left.
+func trimTrailingSpace(nodes []Node) []Node {
+	if len(nodes) == 0 {
+		return nodes
 	}
 
-	return &unorderedList{Properties: properties, ListItems: listElements}
+	if fragment, ok := nodes[len(nodes)-1].(*Fragment); ok {
+		fragment.Value = strings.TrimRight(fragment.Value, " \t")
+		if len(fragment.Value) == 0 {
+			return nodes[:len(nodes)-1]
+		}
+	}
+	return nodes
 }
 
-func (p *parser) parseImage(properties []property) component {
+func (p *parser) parseImage(properties []Property) Node {
+	start := p.currentTok.Pos
 	p.nextToken()
 	p.nextToken()
 
@@ -712,12 +1337,12 @@ func (p *parser) parseImage(properties []property) component {
 		p.nextToken()
 
 		if p.curTokenIs(newline) || p.curTokenIs(eof) {
-			return &paragraph{Properties: properties, Content: []component{&fragment{Value: "![" + altText}}}
+			return &Paragraph{Properties: properties, Content: []Node{&Fragment{Value: "![" + altText}}}
 		}
 	}
 
 	if !p.peekTokenIs(lparen) {
-		return &paragraph{Properties: properties, Content: []component{&fragment{Value: "![" + altText + "]"}}}
+		return &Paragraph{Properties: properties, Content: []Node{&Fragment{Value: "![" + altText + "]"}}}
 	}
 
 	p.nextToken()
@@ -729,47 +1354,52 @@ func (p *parser) parseImage(properties []property) component {
 		p.nextToken()
 
 		if p.curTokenIs(newline) || p.curTokenIs(eof) {
-			fragment := &fragment{Value: "![" + altText + "](" + urlString}
-			return &paragraph{Properties: properties, Content: []component{fragment}}
+			fragment := &Fragment{Value: "![" + altText + "](" + urlString}
+			return &Paragraph{Properties: properties, Content: []Node{fragment}}
 		}
 	}
 
-	return &image{Properties: properties, ImgUrl: urlString, AltText: altText}
+	return &Image{Properties: properties, ImgUrl: urlString, AltText: altText, SourceRange: p.rangeThrough(start)}
 }
 
-func (p *parser) parseDiv(properties []property) component {
+func (p *parser) parseDiv(properties []Property) Node {
+	start := p.currentTok.Pos
 	p.nextToken()
 	for p.curTokenIs(newline) {
 		p.nextToken()
 	}
 
 	components, err := p.parse(rbracket)
-	p.nextToken()
 	if err != nil {
-		panic(err.Error())
+		p.fail(err)
+		return nil
 	}
 
+	sourceRange := p.rangeThrough(start)
+	p.nextToken()
+
 	if p.peekTokenIs(newline) {
 		p.nextToken()
 	}
 
-	return &div{Properties: properties, Children: components}
+	return &Div{Properties: properties, Children: components, SourceRange: sourceRange}
 }
 
-func (p *parser) parseLink(properties []property) component {
+func (p *parser) parseLink(properties []Property) Node {
 	p.nextToken()
 
 	components, err := p.parse(rbracket)
 	if err != nil {
-		panic(err.Error())
+		p.fail(err)
+		return nil
 	}
 
 	if !p.peekTokenIs(lparen) {
-		content := make([]component, 0)
-		content = append(content, &fragment{Value: "["})
+		content := make([]Node, 0)
+		content = append(content, &Fragment{Value: "["})
 		content = append(content, components...)
-		content = append(content, &fragment{Value: "]"})
-		return &paragraph{Content: content}
+		content = append(content, &Fragment{Value: "]"})
+		return &Paragraph{Content: content}
 	}
 
 	p.nextToken()
@@ -781,30 +1411,31 @@ func (p *parser) parseLink(properties []property) component {
 		p.nextToken()
 
 		if p.curTokenIs(newline) || p.curTokenIs(eof) {
-			content := make([]component, 0)
-			content = append(content, &fragment{Value: "["})
+			content := make([]Node, 0)
+			content = append(content, &Fragment{Value: "["})
 			content = append(content, components...)
-			content = append(content, &fragment{Value: "](" + urlString})
-			return &paragraph{Content: content}
+			content = append(content, &Fragment{Value: "](" + urlString})
+			return &Paragraph{Content: content}
 		}
 	}
 
 	// if only child is a simple paragraph, replace with a fragment for cleaner output
 	if len(components) == 1 {
-		if p, ok := components[0].(*paragraph); ok {
+		if p, ok := components[0].(*Paragraph); ok {
 			if len(p.Content) == 1 {
-				if frag, ok := p.Content[0].(*fragment); ok {
-					components = []component{frag}
+				if frag, ok := p.Content[0].(*Fragment); ok {
+					components = []Node{frag}
 				}
 			}
 		}
 	}
 
 	p.nextToken()
-	return &link{Properties: properties, Url: urlString, Content: components}
+	return &Link{Properties: properties, Url: urlString, Content: components}
 }
 
-func (p *parser) parseShortLink(properties []property) component {
+func (p *parser) parseShortLink(properties []Property) Node {
+	start := p.currentTok.Pos
 	p.nextToken()
 
 	var urlString string
@@ -813,27 +1444,32 @@ func (p *parser) parseShortLink(properties []property) component {
 		p.nextToken()
 
 		if p.curTokenIs(newline) || p.curTokenIs(eof) {
-			return &paragraph{Properties: properties, Content: []component{&fragment{Value: "<" + urlString}}}
+			return &Paragraph{Properties: properties, Content: []Node{&Fragment{Value: "<" + urlString}}}
 		}
 	}
 
-	return &link{Properties: properties, Url: urlString, Content: []component{&fragment{Value: urlString}}}
+	sourceRange := p.rangeThrough(start)
+	content := &Fragment{Value: urlString, SourceRange: SourceRange{Start: start, End: p.currentTok.Pos}}
+	content.Start.Offset++
+	content.Start.Column++
+	return &Link{Properties: properties, Url: urlString, Content: []Node{content}, SourceRange: sourceRange}
 }
 
-func (p *parser) parseButton(properties []property) component {
+func (p *parser) parseButton(properties []Property) Node {
 	p.nextToken()
 	p.nextToken()
 
 	components, err := p.parse(rbracket)
 	if err != nil {
-		panic(err.Error())
+		p.fail(err)
+		return nil
 	}
 
 	if !p.peekTokenIs(lparen) {
-		content := []component{&fragment{Value: "~["}}
+		content := []Node{&Fragment{Value: "~["}}
 		content = append(content, components...)
-		content = append(content, &fragment{Value: "]"})
-		return &paragraph{Content: content}
+		content = append(content, &Fragment{Value: "]"})
+		return &Paragraph{Content: content}
 	}
 
 	p.nextToken()
@@ -845,42 +1481,44 @@ func (p *parser) parseButton(properties []property) component {
 		p.nextToken()
 
 		if p.curTokenIs(newline) || p.curTokenIs(eof) {
-			content := []component{&fragment{Value: "~["}}
+			content := []Node{&Fragment{Value: "~["}}
 			content = append(content, components...)
-			content = append(content, &fragment{Value: "](" + onClick})
-			return &paragraph{Content: content}
+			content = append(content, &Fragment{Value: "](" + onClick})
+			return &Paragraph{Content: content}
 		}
 	}
 
 	p.nextToken()
 
-	return &button{Properties: properties, OnClick: onClick, Content: components}
+	return &Button{Properties: properties, OnClick: onClick, Content: components}
 }
 
-func (p *parser) parseNav(properties []property) component {
-	children := make([]component, 0)
+func (p *parser) parseNav(properties []Property) Node {
+	start := p.currentTok.Pos
+	children := make([]Node, 0)
 
 	p.nextToken()
 	components, err := p.parse(at)
 	if err != nil {
-		panic(err.Error())
+		p.fail(err)
+		return nil
 	}
 
 	for _, component := range components {
 		// don't put line breaks in nav element
-		if _, ok := component.(*lineBreak); !ok {
+		if _, ok := component.(*LineBreak); !ok {
 			children = append(children, component)
 		}
 	}
 
-	return &nav{Properties: properties, Children: children}
+	return &Nav{Properties: properties, Children: children, SourceRange: p.rangeThrough(start)}
 }
 
-func (p *parser) parseSpan(properties []property, closing tokenType) component {
+func (p *parser) parseSpan(properties []Property, closing tokenType) Node {
 	if p.peekTokenIs(newline) || p.peekTokenIs(eof) {
 		content := p.parseTextLine(closing)
 		p.nextToken()
-		return &paragraph{Properties: properties, Content: []component{&fragment{Value: content}}}
+		return &Paragraph{Properties: properties, Content: []Node{&Fragment{Value: content}}}
 	}
 
 	p.nextToken()
@@ -891,40 +1529,432 @@ func (p *parser) parseSpan(properties []property, closing tokenType) component {
 
 	// remove trailing whitespace if last component is fragment
 	if len(content) > 0 {
-		if frag, ok := content[len(content)-1].(*fragment); ok {
+		if frag, ok := content[len(content)-1].(*Fragment); ok {
 			frag.Value = strings.TrimRight(frag.Value, " ")
 		}
 	}
 
-	p.nextToken()
-	return &span{Properties: properties, Content: content}
+	// a span left open at the end of the line stops there, so that the line break isn't lost
+	if p.curTokenIs(dollar) {
+		p.nextToken()
+	}
+	return &Span{Properties: properties, Content: content}
 }
 
-func (p *parser) parseCodeBlock(properties []property) component {
+func (p *parser) parseCodeBlock(properties []Property) Node {
+	opening := p.currentTok
+	start := p.currentTok.Pos
 	p.nextToken()
 	p.nextToken()
 
 	var codeBlockString string
 	for !(p.curTokenIs(caret) && p.peekTokenIs(caret)) {
-		codeBlockString += p.currentTok.Literal
+		switch p.currentTok.Type {
+		case newline:
+			codeBlockString += "\n"
+		case tab:
+			codeBlockString += "\t"
+		default:
+			codeBlockString += p.currentTok.Literal
+		}
 		p.nextToken()
 
 		if p.curTokenIs(eof) {
-			fragment := &fragment{Value: "^^" + codeBlockString}
-			paragraph := &paragraph{Properties: properties, Content: []component{fragment}}
+			fragment := &Fragment{Value: "^^" + strings.ReplaceAll(codeBlockString, "\n", " ")}
+			paragraph := &Paragraph{Properties: properties, Content: []Node{fragment}}
 			return paragraph
 		}
 	}
 	p.nextToken()
 
-	codeBlockString = strings.ReplaceAll(codeBlockString, "\\t", "    ")
-	codeBlockString = strings.TrimPrefix(codeBlockString, "\\n")
-	codeBlockString = strings.TrimSuffix(codeBlockString, "\\n")
-	return &codeBlock{Properties: properties, Content: codeBlockString}
+	// code indented along with its opening ^^, such as under a list item, is written without that indentation
+	lineStart := strings.LastIndexAny(p.lex.input[:start.Offset], "\r\n") + 1
+	if indentation := p.lex.input[lineStart:start.Offset]; isIndentation(indentation) && len(indentation) > 0 {
+		lines := strings.Split(codeBlockString, "\n")
+		for i := range lines {
+			lines[i] = strings.TrimPrefix(lines[i], indentation)
+		}
+		codeBlockString = strings.Join(lines, "\n")
+	}
+
+	codeBlockString = strings.ReplaceAll(codeBlockString, "\t", "    ")
+	codeBlockString = strings.TrimPrefix(codeBlockString, "\n")
+	codeBlockString = strings.TrimSuffix(codeBlockString, "\n")
+	codeBlock := &CodeBlock{Content: codeBlockString, SourceRange: p.rangeThrough(start)}
+	if include := p.setCodeBlockOptions(codeBlock, properties); len(include.file) > 0 {
+		p.includeCode(opening, codeBlock, include)
+	}
+	return codeBlock
+}
+
+// Sets the options of codeBlock from the properties that configure it, such as lang and title, and the rest as its
+// Properties. Options with invalid values are left unset and added to the warnings. Returns the file the code block
+// includes, if any.
+func (p *parser) setCodeBlockOptions(codeBlock *CodeBlock, properties []Property) codeInclude {
+	var include codeInclude
+	warn := func(property Property, expected string) {
+		message := fmt.Sprintf("Invalid code block property %s=%q, expected %s", property.Name, property.Value, expected)
+		p.warnings = append(p.warnings, Warning{Filename: p.filename, Pos: codeBlock.Start, Message: message})
+	}
+
+	for _, property := range properties {
+		switch strings.ToLower(property.Name) {
+		case "lang":
+			codeBlock.Language = property.Value
+		case "lines":
+			switch strings.ToLower(property.Value) {
+			case "", "true":
+				codeBlock.LineNumbers = true
+			case "false":
+				codeBlock.LineNumbers = false
+			default:
+				warn(property, "true or false")
+			}
+		case "start":
+			if number, err := strconv.Atoi(property.Value); err == nil && number > 0 {
+				codeBlock.StartLine = number
+			} else {
+				warn(property, "a line number")
+			}
+		case "highlight":
+			if ranges, ok := parseLineRanges(property.Value); ok {
+				codeBlock.HighlightLines = ranges
+			} else {
+				warn(property, "line numbers and ranges such as 3-5,9")
+			}
+		case "title":
+			codeBlock.Title = property.Value
+		case "file":
+			include.file = property.Value
+		case "range":
+			include.lines = property.Value
+		case "region":
+			include.region = property.Value
+		default:
+			codeBlock.Properties = append(codeBlock.Properties, property)
+		}
+	}
+	return include
+}
+
+// Replaces the content of codeBlock with the code it includes from a file, failing if the file or the part of it
+// can't be found. Files are only included when parsing a file, relative to its directory.
+func (p *parser) includeCode(opening token, codeBlock *CodeBlock, include codeInclude) {
+	if len(strings.TrimSpace(codeBlock.Content)) > 0 {
+		p.fail(p.errorAt(opening, "Code block including a file must be empty"))
+		return
+	}
+
+	if len(p.filename) == 0 {
+		p.fail(p.errorAt(opening, fmt.Sprintf("Can't include %q, code is only included when transforming a file", include.file)))
+		return
+	}
+
+	code, firstLine, includeErr := readInclude(p.filename, include)
+	if includeErr != nil {
+		p.fail(p.errorAt(opening, includeErr.Error()))
+		return
+	}
+
+	codeBlock.Source = include.file
+	codeBlock.Content = code
+	if codeBlock.StartLine == 0 {
+		codeBlock.StartLine = firstLine
+	}
+}
+
+// Parses comma separated line numbers and ranges of them, such as 3-5,9.
+func parseLineRanges(s string) ([]LineRange, bool) {
+	ranges := make([]LineRange, 0)
+	for _, part := range strings.Split(s, ",") {
+		startString, endString, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, startErr := strconv.Atoi(strings.TrimSpace(startString))
+		end, endErr := start, error(nil)
+		if isRange {
+			end, endErr = strconv.Atoi(strings.TrimSpace(endString))
+		}
+
+		if startErr != nil || endErr != nil || start < 1 || end < start {
+			return nil, false
+		}
+		ranges = append(ranges, LineRange{Start: start, End: end})
+	}
+	return ranges, true
+}
+
+// Parses a fenced code block, moving to the last token of its closing fence.
+func (p *parser) parseFencedCodeBlock(properties []Property, fence codeFence) Node {
+	start := p.currentTok.Pos
+	for p.nextTok.Pos.Offset < fence.end && !p.peekTokenIs(eof) {
+		p.nextToken()
+	}
+
+	return &FencedCodeBlock{Properties: properties, Language: fence.language, Content: fence.content, SourceRange: p.rangeThrough(start)}
 }
 
 func (p *parser) parseComment() {
-	for !p.curTokenIs(newline) {
+	for !(p.curTokenIs(newline) || p.curTokenIs(eof)) {
 		p.nextToken()
 	}
 }
+
+// Parses a table starting at the | opening its header row. The header row is followed by a delimiter row setting
+// the alignment of each column, and then by body rows for as long as lines start with a |.
+func (p *parser) parseTable(properties []Property) Node {
+	start := p.currentTok.Pos
+	alignments, _ := p.tableAlignments(start.Offset)
+
+	header := p.parseTableRow(alignments, true)
+
+	// the delimiter row only sets alignments, which have already been read
+	p.nextToken()
+	for !(p.curTokenIs(newline) || p.curTokenIs(eof)) {
+		p.nextToken()
+	}
+
+	rows := make([]TableRow, 0)
+	for p.err == nil && p.isNextLineTableRow() {
+		p.nextToken()
+		for p.curTokenIs(space) || p.curTokenIs(tab) {
+			p.nextToken()
+		}
+		rows = append(rows, p.parseTableRow(alignments, false))
+	}
+
+	return &Table{Properties: properties, Header: header, Rows: rows, SourceRange: p.rangeFrom(start)}
+}
+
+// Parses the cells of the row starting at the current |, with properties for the row written after its last |.
+// Rows are padded with empty cells or cut short to have one cell per column.
+func (p *parser) parseTableRow(alignments []Alignment, header bool) TableRow {
+	start := p.currentTok.Pos
+	row := TableRow{Cells: make([]TableCell, 0, len(alignments))}
+	p.nextToken()
+
+	for !(p.curTokenIs(newline) || p.curTokenIs(eof)) && p.err == nil {
+		for p.curTokenIs(space) || p.curTokenIs(tab) {
+			p.nextToken()
+		}
+		if p.curTokenIs(newline) || p.curTokenIs(eof) {
+			break
+		}
+
+		cellStart := p.currentTok.Pos
+		var properties []Property
+		if p.curTokenIs(lsquirly) {
+			var err error
+			properties, err, _ = p.parsePropertyList()
+			if err != nil {
+				p.fail(err)
+				break
+			}
+			p.nextToken()
+			for p.curTokenIs(space) || p.curTokenIs(tab) {
+				p.nextToken()
+			}
+
+			if p.curTokenIs(newline) || p.curTokenIs(eof) {
+				row.Properties = properties
+				break
+			}
+		}
+
+		content := trimTrailingSpace(p.parseLine(pipe))
+		p.countNodes(1)
+		row.Cells = append(row.Cells, TableCell{Properties: properties, Header: header, Content: content, SourceRange: p.rangeFrom(cellStart)})
+		if p.curTokenIs(pipe) {
+			p.nextToken()
+		}
+	}
+
+	if len(row.Cells) > len(alignments) {
+		row.Cells = row.Cells[:len(alignments)]
+	}
+	for len(row.Cells) < len(alignments) {
+		p.countNodes(1)
+		row.Cells = append(row.Cells, TableCell{Header: header, Content: []Node{}})
+	}
+	for i := range row.Cells {
+		row.Cells[i].Align = alignments[i]
+	}
+
+	p.countNodes(1)
+	row.SourceRange = p.rangeFrom(start)
+	return row
+}
+
+// Reports whether the current token ends a line and the next line is a table row, starting with a | after any
+// indentation.
+func (p *parser) isNextLineTableRow() bool {
+	if !p.curTokenIs(newline) {
+		return false
+	}
+	_, i := indentationAt(p.lex.input, p.nextTok.Pos.Offset)
+	return i < len(p.lex.input) && p.lex.input[i] == '|'
+}
+
+// Returns the column alignments of the table whose header row starts at offset, or false if the line starting at
+// offset isn't followed by a delimiter row such as | :--- | :---: | ---: |.
+func (p *parser) tableAlignments(offset int) ([]Alignment, bool) {
+	input := p.lex.input
+	lineStart := strings.LastIndexAny(input[:offset], "\r\n") + 1
+	if offset >= len(input) || input[offset] != '|' || !isIndentation(input[lineStart:offset]) {
+		return nil, false
+	}
+
+	delimiterStart := nextLineStart(input, offset)
+	if delimiterStart < 0 {
+		return nil, false
+	}
+	delimiterEnd := nextLineStart(input, delimiterStart)
+	if delimiterEnd < 0 {
+		delimiterEnd = len(input)
+	}
+	return parseDelimiterRow(input[delimiterStart:delimiterEnd])
+}
+
+// Returns the offset of the start of the line after the one containing offset, or -1 if it is the last line.
+func nextLineStart(input string, offset int) int {
+	end := lineEnd(input, offset)
+	if end == len(input) {
+		return -1
+	}
+
+	if strings.HasPrefix(input[end:], "\r\n") {
+		return end + 2
+	}
+	return end + 1
+}
+
+// Returns the alignment of each column in a delimiter row, where a colon on the left, right or both ends of the dashes
+// aligns the column that way.
+func parseDelimiterRow(line string) ([]Alignment, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "|") {
+		return nil, false
+	}
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+
+	columns := strings.Split(line, "|")
+	alignments := make([]Alignment, 0, len(columns))
+	for _, column := range columns {
+		column = strings.TrimSpace(column)
+		dashes, left := strings.CutPrefix(column, ":")
+		dashes, right := strings.CutSuffix(dashes, ":")
+		if len(dashes) == 0 || len(strings.Trim(dashes, "-")) > 0 {
+			return nil, false
+		}
+
+		switch {
+		case left && right:
+			alignments = append(alignments, AlignCenter)
+		case left:
+			alignments = append(alignments, AlignLeft)
+		case right:
+			alignments = append(alignments, AlignRight)
+		default:
+			alignments = append(alignments, AlignNone)
+		}
+	}
+	return alignments, true
+}
+
+// A fenced code block found by looking ahead in the source.
+type codeFence struct {
+	language string
+	content  string
+	// offset just past the closing fence
+	end int
+}
+
+// A fence found to have no closing fence, which rules out a closing fence for any later fence of the same character
+// that is at least as long.
+type unclosedFence struct {
+	offset int
+	length int
+}
+
+// Returns the fenced code block opening at offset, with a line of three or more backticks or tildes, the first word
+// after them naming the language. The block ends at a line of at least as many of the same character, and its lines
+// are written without the indentation of the opening fence. Returns false if offset doesn't start a line that opens a
+// fence, or the fence is never closed.
+func (p *parser) codeFenceAt(offset int) (codeFence, bool) {
+	var fence codeFence
+	input := p.lex.input
+	lineStart := strings.LastIndexAny(input[:offset], "\r\n") + 1
+	indentation := input[lineStart:offset]
+	if offset >= len(input) || (input[offset] != '`' && input[offset] != '~') || !isIndentation(indentation) {
+		return fence, false
+	}
+
+	fenceChar := input[offset]
+	fenceLength := len(input[offset:]) - len(strings.TrimLeft(input[offset:], string(fenceChar)))
+	if fenceLength < 3 {
+		return fence, false
+	}
+
+	contentStart := nextLineStart(input, offset)
+	if contentStart < 0 {
+		return fence, false
+	}
+
+	info := strings.TrimSpace(input[offset+fenceLength : lineEnd(input, offset)])
+	if fenceChar == '`' && strings.Contains(info, "`") {
+		// ```a` is inline code rather than a fence
+		return fence, false
+	}
+
+	if unclosed, ok := p.unclosedFences[fenceChar]; ok && unclosed.offset <= offset && unclosed.length <= fenceLength {
+		return fence, false
+	}
+
+	closingStart := -1
+	lineCount := 0
+	for i := contentStart; i >= 0 && i < len(input); i = nextLineStart(input, i) {
+		lineCount++
+		if p.ctx != nil && lineCount%contextCheckInterval == 0 {
+			if err := p.ctx.Err(); err != nil {
+				p.fail(err)
+				return fence, false
+			}
+		}
+
+		closing := strings.TrimLeft(input[i:lineEnd(input, i)], " \t")
+		closingLength := len(closing) - len(strings.TrimLeft(closing, string(fenceChar)))
+		if closingLength >= fenceLength && len(strings.TrimSpace(closing[closingLength:])) == 0 {
+			closingStart = i
+			break
+		}
+	}
+
+	if closingStart < 0 {
+		if p.unclosedFences == nil {
+			p.unclosedFences = make(map[byte]unclosedFence)
+		}
+		if unclosed, ok := p.unclosedFences[fenceChar]; !ok || fenceLength < unclosed.length {
+			p.unclosedFences[fenceChar] = unclosedFence{offset: offset, length: fenceLength}
+		}
+		return fence, false
+	}
+
+	lines := make([]string, 0)
+	for i := contentStart; i < closingStart; i = nextLineStart(input, i) {
+		lines = append(lines, strings.TrimPrefix(input[i:lineEnd(input, i)], indentation))
+	}
+
+	if fields := strings.Fields(info); len(fields) > 0 {
+		fence.language = fields[0]
+	}
+	fence.content = strings.Join(lines, "\n")
+	fence.end = closingStart + len(strings.TrimRight(input[closingStart:lineEnd(input, closingStart)], " \t"))
+	return fence, true
+}
+
+// Returns the offset of the line break ending the line containing offset, or the length of input on the last line.
+func lineEnd(input string, offset int) int {
+	end := strings.IndexAny(input[offset:], "\r\n")
+	if end < 0 {
+		return len(input)
+	}
+	return offset + end
+}
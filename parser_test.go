@@ -1,12 +1,16 @@
 package mdx
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
-func execute(t *testing.T, input string) []component {
+func execute(t *testing.T, input string) []Node {
 	t.Helper()
 	elements, parseErr := newParser(newLexer(input)).parse(eof)
 	if parseErr != nil {
@@ -20,6 +24,19 @@ func fail(t *testing.T, message string) {
 	t.Errorf("%s failed: %s", t.Name(), message)
 }
 
+// Clears the source range of every node so parsed nodes can be compared against literals.
+func clearRanges(nodes []Node) []Node {
+	for _, node := range nodes {
+		Walk(node, VisitorFunc(func(n Node, entering bool) (Node, WalkStatus) {
+			if r, ok := n.(interface{ setRange(SourceRange) }); ok && entering {
+				r.setRange(SourceRange{})
+			}
+			return n, WalkContinue
+		}))
+	}
+	return nodes
+}
+
 func validateLength(t *testing.T, actual, expected int) {
 	t.Helper()
 	if actual != expected {
@@ -34,7 +51,7 @@ func TestParseProperties(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if p, ok := element.(*paragraph); ok {
+	if p, ok := element.(*Paragraph); ok {
 		properties := p.Properties
 
 		validateLength(t, len(properties), 1)
@@ -58,10 +75,10 @@ func TestParsePropertiesInline(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if p, ok := element.(*paragraph); ok {
+	if p, ok := element.(*Paragraph); ok {
 		validateLength(t, len(p.Content), 2)
 
-		if frag, ok := p.Content[0].(*fragment); ok {
+		if frag, ok := p.Content[0].(*Fragment); ok {
 			if frag.Value != "Hello, " {
 				fail(t, fmt.Sprintf("Expected 'Hello, ', got=%s", frag.Value))
 			}
@@ -69,7 +86,7 @@ func TestParsePropertiesInline(t *testing.T) {
 			fail(t, fmt.Sprintf("Expected Fragment, got=%T", p.Content[0]))
 		}
 
-		if s, ok := p.Content[1].(*span); ok {
+		if s, ok := p.Content[1].(*Span); ok {
 			validateLength(t, len(s.Content), 1)
 			validateLength(t, len(s.Properties), 1)
 
@@ -91,6 +108,163 @@ func TestParsePropertiesInline(t *testing.T) {
 
 }
 
+func TestParseQuotedProperties(t *testing.T) {
+	input := `{ .style="color: red; margin: 0" .alt='A "cat" = } ok' .title="say \"hi\" \\ bye" }
+# Hello`
+	elements := execute(t, input)
+	validateLength(t, len(elements), 1)
+
+	heading, ok := elements[0].(*Heading)
+	if !ok {
+		fail(t, fmt.Sprintf("Expected Heading, got=%T", elements[0]))
+		t.FailNow()
+	}
+
+	expected := []Property{
+		{Name: "style", Value: "color: red; margin: 0"},
+		{Name: "alt", Value: "A \"cat\" = } ok"},
+		{Name: "title", Value: "say \"hi\" \\ bye"},
+	}
+	validateLength(t, len(heading.Properties), len(expected))
+	for i, property := range heading.Properties {
+		if property != expected[i] {
+			fail(t, fmt.Sprintf("Expected %+v, got=%+v", expected[i], property))
+		}
+	}
+}
+
+func TestParseUnterminatedQuoteError(t *testing.T) {
+	_, err := parseWithTimeout(t, "{ .title=\"oops }\n# Hello")
+	expected := "1:10: Property formatted incorrectly. QUOTE is missing a closing QUOTE\n{ .title=\"oops }\n         ^"
+	if err == nil || err.Error() != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%v", expected, err))
+	}
+
+	elements := execute(t, "Hello { .title=\"oops } world")
+	validateLength(t, len(elements), 1)
+	paragraph := elements[0].(*Paragraph)
+	validateLength(t, len(paragraph.Content), 1)
+	if frag, ok := paragraph.Content[0].(*Fragment); !ok || frag.Value != "Hello { .title=\"oops } world" {
+		fail(t, fmt.Sprintf("Expected inline properties to stay as text, got=%+v", paragraph.Content[0]))
+	}
+}
+
+func TestParseQuotesOutsideProperties(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"set `x=\"` then **b** and `y`",
+			"<p>set <code>x=\"</code> then <strong>b</strong> and <code>y</code></p>",
+		},
+		{"[link](http://x/?a=\"b) c)", "<a href=\"http://x/?a=&#34;b\" target=_blank>link</a><p>c)</p>"},
+		{"x=\"**bold**\"", "<p>x=\"<strong>bold</strong>\"</p>"},
+	}
+
+	for _, test := range tests {
+		elements, err := parseWithTimeout(t, test.input)
+		if err != nil {
+			fail(t, err.Error())
+			continue
+		}
+
+		if actual := rawString(elements...); actual != test.expected {
+			fail(t, fmt.Sprintf("Expected %q for %q, got=%q", test.expected, test.input, actual))
+		}
+	}
+}
+
+func TestParseSelectorProperties(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Property
+	}{
+		{"{ #intro }\n# Hello", []Property{{Name: "id", Value: "intro"}}},
+		{"{ .card.highlight }\n# Hello", []Property{{Name: "class", Value: "card highlight"}}},
+		{"{ #intro.card }\n# Hello", []Property{{Name: "id", Value: "intro"}, {Name: "class", Value: "card"}}},
+		{"{ .hidden .data-x=1 }\n# Hello", []Property{{Name: "hidden"}, {Name: "data-x", Value: "1"}}},
+		{"{.hidden} # Hello", []Property{{Name: "hidden"}}},
+		{"{ #intro} # Hello", []Property{{Name: "id", Value: "intro"}}},
+		{"{.card.highlight .data-x=1}\n# Hello", []Property{{Name: "class", Value: "card highlight"}, {Name: "data-x", Value: "1"}}},
+		{
+			"{ .class=a .card.highlight #one .id=two }\n# Hello",
+			[]Property{{Name: "class", Value: "a card highlight"}, {Name: "id", Value: "two"}},
+		},
+	}
+
+	for _, test := range tests {
+		elements := execute(t, test.input)
+		validateLength(t, len(elements), 1)
+
+		heading, ok := elements[0].(*Heading)
+		if !ok {
+			fail(t, fmt.Sprintf("Expected Heading, got=%T", elements[0]))
+			continue
+		}
+
+		if !reflect.DeepEqual(heading.Properties, test.expected) {
+			fail(t, fmt.Sprintf("Expected %+v for %q, got=%+v", test.expected, test.input, heading.Properties))
+		}
+	}
+}
+
+func TestParseBooleanButtonProperty(t *testing.T) {
+	input := "{ .disabled } ~[Click](handleClick)"
+	elements := execute(t, input)
+	validateLength(t, len(elements), 1)
+
+	button, ok := elements[0].(*Button)
+	if !ok {
+		fail(t, fmt.Sprintf("Expected Button, got=%T", elements[0]))
+		t.FailNow()
+	}
+
+	if !reflect.DeepEqual(button.Properties, []Property{{Name: "disabled"}}) {
+		fail(t, fmt.Sprintf("Expected disabled property, got=%+v", button.Properties))
+	}
+}
+
+func TestParseTextResemblingProperties(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"replacing the {{ .Content }} line.", "replacing the {{ .Content }} line."},
+		{"a { .hidden } b", "a { .hidden } b"},
+		{"a { .hidden }", "a { .hidden }"},
+	}
+
+	for _, test := range tests {
+		elements := clearRanges(execute(t, test.input))
+		expected := []Node{&Paragraph{Content: []Node{&Fragment{Value: test.expected}}}}
+		if !reflect.DeepEqual(elements, expected) {
+			fail(t, fmt.Sprintf("Expected %#v for %q, got=%#v", expected, test.input, elements))
+		}
+	}
+}
+
+func TestParsePropertiesAfterHeadingHashes(t *testing.T) {
+	input := "# { .class=section-header } Building a Blog\n{ #intro }\n## {.class=sub} Intro"
+	elements := clearRanges(execute(t, input))
+	expected := []Node{
+		&Heading{
+			Level:      1,
+			Properties: []Property{{Name: "class", Value: "section-header"}},
+			Content:    []Node{&Fragment{Value: "Building a Blog"}},
+		},
+		&Heading{
+			Level:      2,
+			Properties: []Property{{Name: "id", Value: "intro"}, {Name: "class", Value: "sub"}},
+			Content:    []Node{&Fragment{Value: "Intro"}},
+		},
+	}
+
+	if !reflect.DeepEqual(elements, expected) {
+		fail(t, fmt.Sprintf("Expected=%#v, got=%#v", expected, elements))
+	}
+}
+
 func TestParseNestedProperties(t *testing.T) {
 	input := `# Hello
 { .class=container }
@@ -103,7 +277,7 @@ func TestParseNestedProperties(t *testing.T) {
 	validateLength(t, len(elements), 2)
 	element := elements[1]
 
-	if div, ok := element.(*div); ok {
+	if div, ok := element.(*Div); ok {
 		validateLength(t, len(div.Properties), 1)
 
 		divProperty := div.Properties[0]
@@ -118,7 +292,7 @@ func TestParseNestedProperties(t *testing.T) {
 		validateLength(t, len(div.Children), 2)
 
 		child := div.Children[1]
-		if p, ok := child.(*paragraph); ok {
+		if p, ok := child.(*Paragraph); ok {
 			validateLength(t, len(p.Properties), 2)
 
 			if p.Properties[0].Name != "class" {
@@ -150,7 +324,7 @@ func TestParseHeader1(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if head, ok := element.(*header); ok {
+	if head, ok := element.(*Heading); ok {
 		if head.Level != 1 {
 			fail(t, fmt.Sprintf("Expected Header level 1, got=%d", head.Level))
 		}
@@ -169,7 +343,7 @@ func TestParseHeader2(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if head, ok := element.(*header); ok {
+	if head, ok := element.(*Heading); ok {
 		if head.Level != 2 {
 			fail(t, fmt.Sprintf("Expected Header level 1, got=%d", head.Level))
 		}
@@ -190,7 +364,7 @@ MDX`
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if head, ok := element.(*header); ok {
+	if head, ok := element.(*Heading); ok {
 		if head.Level != 1 {
 			fail(t, fmt.Sprintf("Expected Header level 1, got=%d", head.Level))
 		}
@@ -209,14 +383,14 @@ func TestParseHeaderWithNestedElements(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if head, ok := element.(*header); ok {
+	if head, ok := element.(*Heading); ok {
 		if head.Level != 1 {
 			fail(t, fmt.Sprintf("Expected Header level 1, got=%d", head.Level))
 		}
 
 		validateLength(t, len(head.Content), 2)
 
-		if frag, ok := head.Content[0].(*fragment); ok {
+		if frag, ok := head.Content[0].(*Fragment); ok {
 			if frag.Value != "Hello " {
 				fail(t, fmt.Sprintf("Expected 'Hello ', got=%s", frag.Value))
 			}
@@ -224,7 +398,7 @@ func TestParseHeaderWithNestedElements(t *testing.T) {
 			fail(t, fmt.Sprintf("Expected Fragment child, got=%T", element))
 		}
 
-		if strong, ok := head.Content[1].(*bold); ok {
+		if strong, ok := head.Content[1].(*Bold); ok {
 			if strong.InnerHtml() != "world" {
 				fail(t, fmt.Sprintf("Expected 'world', got=%s", strong.InnerHtml()))
 			}
@@ -242,7 +416,7 @@ func TestParseParagraph(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if p, ok := element.(*paragraph); ok {
+	if p, ok := element.(*Paragraph); ok {
 		if p.InnerHtml() != "Hello, world" {
 			fail(t, fmt.Sprintf("Expected 'Hello, world', got=%s", p.InnerHtml()))
 		}
@@ -260,7 +434,7 @@ Paragraph test
 
 	element := elements[1]
 
-	if p, ok := element.(*paragraph); ok {
+	if p, ok := element.(*Paragraph); ok {
 		if p.InnerHtml() != "Paragraph test" {
 			fail(t, fmt.Sprintf("Expected 'Paragraph test', got=%s", p.InnerHtml()))
 		}
@@ -275,10 +449,10 @@ func TestParseParagraphWithNestedElements(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if p, ok := element.(*paragraph); ok {
+	if p, ok := element.(*Paragraph); ok {
 		validateLength(t, len(p.Content), 2)
 
-		if frag, ok := p.Content[0].(*fragment); ok {
+		if frag, ok := p.Content[0].(*Fragment); ok {
 			if frag.Value != "Hello, " {
 				fail(t, fmt.Sprintf("Expected fragment 'Hello, ', got=%s", frag.Value))
 			}
@@ -286,7 +460,7 @@ func TestParseParagraphWithNestedElements(t *testing.T) {
 			fail(t, fmt.Sprintf("Expected Fragment child, got=%T", element))
 		}
 
-		if strong, ok := p.Content[1].(*bold); ok {
+		if strong, ok := p.Content[1].(*Bold); ok {
 			if strong.InnerHtml() != "world" {
 				fail(t, fmt.Sprintf("Expected strong 'world', got=%s", strong.InnerHtml()))
 			}
@@ -305,7 +479,7 @@ func TestParseCode(t *testing.T) {
 	validateLength(t, len(elements), 1)
 
 	element := elements[0]
-	if code, ok := element.(*code); ok {
+	if code, ok := element.(*Code); ok {
 		if code.Text != "print('Hello, world!')" {
 			fail(t, fmt.Sprintf("Content incorrect, got=%s", code.Text))
 		}
@@ -319,9 +493,9 @@ func TestParseCode(t *testing.T) {
 	validateLength(t, len(elements), 1)
 
 	element = elements[0]
-	if p, ok := element.(*paragraph); ok {
+	if p, ok := element.(*Paragraph); ok {
 		validateLength(t, len(p.Content), 1)
-		if frag, ok := p.Content[0].(*fragment); ok {
+		if frag, ok := p.Content[0].(*Fragment); ok {
 			if frag.Value != input {
 				fail(t, fmt.Sprintf("Got fragment with string %s", frag.Value))
 			}
@@ -339,10 +513,10 @@ func TestParseCodeBetweenElements(t *testing.T) {
 	validateLength(t, len(elements), 2)
 	element := elements[1]
 
-	if p, ok := element.(*paragraph); ok {
+	if p, ok := element.(*Paragraph); ok {
 		validateLength(t, len(p.Content), 2)
 
-		if code, ok := p.Content[0].(*code); ok {
+		if code, ok := p.Content[0].(*Code); ok {
 			if code.Text != "hello, world" {
 				fail(t, fmt.Sprintf("Expected 'hello, world', got=%s", code.Text))
 			}
@@ -359,13 +533,13 @@ func TestParseCodeWithinElement(t *testing.T) {
 	elements := execute(t, input)
 	validateLength(t, len(elements), 1)
 
-	if p, ok := elements[0].(*paragraph); ok {
+	if p, ok := elements[0].(*Paragraph); ok {
 		if len(p.Content) != 3 {
 			fail(t, fmt.Sprintf("Expected 3 Paragraph children, got=%d", len(p.Content)))
 			t.FailNow()
 		}
 
-		if code, ok := p.Content[1].(*code); ok {
+		if code, ok := p.Content[1].(*Code); ok {
 			if code.Text != "hello, world" {
 				fail(t, fmt.Sprintf("Expected 'hello, world', got=%s", code.Text))
 			}
@@ -381,7 +555,7 @@ func TestParseStrong(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if strong, ok := element.(*bold); ok {
+	if strong, ok := element.(*Bold); ok {
 		if strong.InnerHtml() != "stronk" {
 			fail(t, fmt.Sprintf("Expected text 'stronk', got=%s", strong.InnerHtml()))
 		}
@@ -398,7 +572,7 @@ MDX`
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if strong, ok := element.(*bold); ok {
+	if strong, ok := element.(*Bold); ok {
 		if strong.InnerHtml() != "stronk" {
 			fail(t, fmt.Sprintf("Expected text 'stronk', got=%s", strong.InnerHtml()))
 		}
@@ -412,10 +586,10 @@ func TestParseStrongWithNestedElements(t *testing.T) {
 	elements := execute(t, input)
 	validateLength(t, len(elements), 1)
 
-	if strong, ok := elements[0].(*bold); ok {
+	if strong, ok := elements[0].(*Bold); ok {
 		validateLength(t, len(strong.Content), 3)
 
-		if frag, ok := strong.Content[0].(*fragment); ok {
+		if frag, ok := strong.Content[0].(*Fragment); ok {
 			if frag.Value != "Extreme " {
 				fail(t, fmt.Sprintf("Expected Strong Fragment 1 text='Extreme ', got=%s", frag.Value))
 			}
@@ -423,7 +597,7 @@ func TestParseStrongWithNestedElements(t *testing.T) {
 			fail(t, fmt.Sprintf("Expected Fragment, got=%T", strong.Content[0]))
 		}
 
-		if code, ok := strong.Content[1].(*code); ok {
+		if code, ok := strong.Content[1].(*Code); ok {
 			if code.Text != "coding" {
 				fail(t, fmt.Sprintf("Expected Code text='coding', got=%s", code.Text))
 			}
@@ -431,7 +605,7 @@ func TestParseStrongWithNestedElements(t *testing.T) {
 			fail(t, fmt.Sprintf("Expected Code child, got=%T", strong.Content[1]))
 		}
 
-		if frag, ok := strong.Content[2].(*fragment); ok {
+		if frag, ok := strong.Content[2].(*Fragment); ok {
 			if frag.Value != " time" {
 				fail(t, fmt.Sprintf("Expected Strong Fragment 1 text=' time', got=%s", frag.Value))
 			}
@@ -449,7 +623,7 @@ func TestParseEm(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if em, ok := element.(*italic); ok {
+	if em, ok := element.(*Italic); ok {
 		if em.InnerHtml() != "slinky" {
 			fail(t, fmt.Sprintf("Expected Em text='slinky', got=%s", em.InnerHtml()))
 		}
@@ -466,7 +640,7 @@ MDX`
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if em, ok := element.(*italic); ok {
+	if em, ok := element.(*Italic); ok {
 		if em.InnerHtml() != "slinky" {
 			fail(t, fmt.Sprintf("Expected Em text='slinky', got=%s", em.InnerHtml()))
 		}
@@ -481,10 +655,10 @@ func TestParseEmWithNestedElements(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if em, ok := element.(*italic); ok {
+	if em, ok := element.(*Italic); ok {
 		validateLength(t, len(em.Content), 3)
 
-		if f, ok := em.Content[0].(*fragment); ok {
+		if f, ok := em.Content[0].(*Fragment); ok {
 			if f.Value != "speedy " {
 				fail(t, fmt.Sprintf("Expected Em Fragment text='speedy ', got=%s", f.Value))
 			}
@@ -492,7 +666,7 @@ func TestParseEmWithNestedElements(t *testing.T) {
 			fail(t, fmt.Sprintf("Expected Em Fragment, got=%T", em.Content[0]))
 		}
 
-		if c, ok := em.Content[1].(*code); ok {
+		if c, ok := em.Content[1].(*Code); ok {
 			if c.Text != "coding" {
 				fail(t, fmt.Sprintf("Expected Em Code text='coding', got=%s", c.Text))
 			}
@@ -500,7 +674,7 @@ func TestParseEmWithNestedElements(t *testing.T) {
 			fail(t, fmt.Sprintf("Expected Em Code, got=%T", em.Content[1]))
 		}
 
-		if f, ok := em.Content[2].(*fragment); ok {
+		if f, ok := em.Content[2].(*Fragment); ok {
 			if f.Value != " session" {
 				fail(t, fmt.Sprintf("Expected Em Fragment text=' session', got=%s", f.Value))
 			}
@@ -518,7 +692,7 @@ func TestParseBlockQuote(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if quote, ok := element.(*blockQuote); ok {
+	if quote, ok := element.(*BlockQuote); ok {
 		if quote.InnerHtml() != "Quote me" {
 			fail(t, fmt.Sprintf("Expected blockQuote text='Quote me', got='%s'", quote.InnerHtml()))
 		}
@@ -536,7 +710,7 @@ func TestParseBlockQuoteBetweenElements(t *testing.T) {
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if quote, ok := element.(*blockQuote); ok {
+	if quote, ok := element.(*BlockQuote); ok {
 		if quote.InnerHtml() != "Quote" {
 			fail(t, fmt.Sprintf("Expected blockQuote text='Quote', got=%s", quote.InnerHtml()))
 		}
@@ -551,15 +725,15 @@ func TestParseMultiLayerBlockQuote(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	first := elements[0]
 
-	if quote, ok := first.(*blockQuote); ok {
+	if quote, ok := first.(*BlockQuote); ok {
 		validateLength(t, len(quote.Content), 1)
 
 		second := quote.Content[0]
 
-		if innerQuote, ok := second.(*blockQuote); ok {
+		if innerQuote, ok := second.(*BlockQuote); ok {
 			validateLength(t, len(innerQuote.Content), 1)
 
-			if frag, ok := innerQuote.Content[0].(*fragment); ok {
+			if frag, ok := innerQuote.Content[0].(*Fragment); ok {
 				if frag.Value != "Quote" {
 					fail(t, fmt.Sprintf("Expected fragment text='Quote', got='%s'", frag.Value))
 				}
@@ -587,13 +761,13 @@ func TestParseBlockQuoteWithNestedElements(t *testing.T) {
 
 	element := elements[0]
 
-	if first, ok := element.(*blockQuote); ok {
+	if first, ok := element.(*BlockQuote); ok {
 		if len(first.Content) != 4 {
 			fail(t, fmt.Sprintf("Expected 4 elements, got=%d", len(first.Content)))
 			t.FailNow()
 		}
 
-		if firstFrag, ok := first.Content[0].(*fragment); ok {
+		if firstFrag, ok := first.Content[0].(*Fragment); ok {
 			if firstFrag.Value != "Quote" {
 				fail(t, fmt.Sprintf("Expected fragment text='Quote ', got='%s'", firstFrag.Value))
 			}
@@ -601,7 +775,7 @@ func TestParseBlockQuoteWithNestedElements(t *testing.T) {
 			fail(t, fmt.Sprintf("Expected Fragment, got=%T", first.Content[0]))
 		}
 
-		if space, ok := first.Content[1].(*fragment); ok {
+		if space, ok := first.Content[1].(*Fragment); ok {
 			if space.Value != " " {
 				fail(t, fmt.Sprintf("Expected fragment text=' ', got='%s'", space.Value))
 			}
@@ -609,13 +783,13 @@ func TestParseBlockQuoteWithNestedElements(t *testing.T) {
 			fail(t, fmt.Sprintf("Expected Fragment, got=%T", first.Content[1]))
 		}
 
-		if stronk, ok := first.Content[2].(*bold); ok {
+		if stronk, ok := first.Content[2].(*Bold); ok {
 			if len(stronk.Content) != 1 {
 				fail(t, fmt.Sprintf("Expected 1 bold child, got=%d", len(stronk.Content)))
 				t.FailNow()
 			}
 
-			if stronkFrag, ok := stronk.Content[0].(*fragment); ok {
+			if stronkFrag, ok := stronk.Content[0].(*Fragment); ok {
 				if stronkFrag.Value != "stronk" {
 					fail(t, fmt.Sprintf("Expected bold text='stronk', got='%s'", stronkFrag.Value))
 				}
@@ -626,13 +800,13 @@ func TestParseBlockQuoteWithNestedElements(t *testing.T) {
 			fail(t, fmt.Sprintf("Expected Fragment, got=%T", first.Content[3]))
 		}
 
-		if second, ok := first.Content[3].(*blockQuote); ok {
+		if second, ok := first.Content[3].(*BlockQuote); ok {
 			if len(second.Content) != 3 {
 				fail(t, fmt.Sprintf("Expected 3 second children, got=%d", len(second.Content)))
 				t.FailNow()
 			}
 
-			if firstInner, ok := second.Content[0].(*fragment); ok {
+			if firstInner, ok := second.Content[0].(*Fragment); ok {
 				if firstInner.Value != "Nested" {
 					fail(t, fmt.Sprintf("Expected second text='Nested', got='%s'", firstInner.Value))
 				}
@@ -640,11 +814,11 @@ func TestParseBlockQuoteWithNestedElements(t *testing.T) {
 				fail(t, fmt.Sprintf("Expected Fragment, got=%T", second.Content[0]))
 			}
 
-			if _, ok := second.Content[1].(*lineBreak); !ok {
+			if _, ok := second.Content[1].(*LineBreak); !ok {
 				fail(t, fmt.Sprintf("Expected LineBreak, got=%T", second.Content[1]))
 			}
 
-			if thirdInner, ok := second.Content[2].(*fragment); ok {
+			if thirdInner, ok := second.Content[2].(*Fragment); ok {
 				if thirdInner.Value != "Separated" {
 					fail(t, fmt.Sprintf("Expected second nested text='Separated', got='%s'", thirdInner.Value))
 				}
@@ -666,14 +840,14 @@ func TestParseOrderedList(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if list, ok := element.(*orderedList); ok {
+	if list, ok := element.(*OrderedList); ok {
 		validateLength(t, len(list.ListItems), 2)
 
 		first := list.ListItems[0]
-		if p, ok := first.Component.(*paragraph); ok {
+		if p, ok := first.Component.(*Paragraph); ok {
 			validateLength(t, len(p.Content), 1)
 
-			if pText, ok := p.Content[0].(*fragment); ok {
+			if pText, ok := p.Content[0].(*Fragment); ok {
 				if pText.Value != "First" {
 					fail(t, fmt.Sprintf("Expected item text='First', got='%s'", pText.Value))
 				}
@@ -685,10 +859,10 @@ func TestParseOrderedList(t *testing.T) {
 		}
 
 		second := list.ListItems[1]
-		if p, ok := second.Component.(*paragraph); ok {
+		if p, ok := second.Component.(*Paragraph); ok {
 			validateLength(t, len(p.Content), 1)
 
-			if pText, ok := p.Content[0].(*fragment); ok {
+			if pText, ok := p.Content[0].(*Fragment); ok {
 				if pText.Value != "Second" {
 					fail(t, fmt.Sprintf("Expected item text='Second', got='%s'", pText.Value))
 				}
@@ -703,6 +877,299 @@ func TestParseOrderedList(t *testing.T) {
 	}
 }
 
+func TestParseNestedLists(t *testing.T) {
+	input := "- One\n  - Two\n    1. Three\n\t2. Four\n  - Five\n  { .class=sub }\n  1. Six\n- Seven\nAfter"
+	elements := clearRanges(execute(t, input))
+	validateLength(t, len(elements), 2)
+
+	item := func(text string, children ...Node) ListItem {
+		return ListItem{Component: &Paragraph{Content: []Node{&Fragment{Value: text}}}, Children: children}
+	}
+	expected := &UnorderedList{ListItems: []ListItem{
+		item("One",
+			&UnorderedList{ListItems: []ListItem{
+				item("Two", &OrderedList{Start: 1, ListItems: []ListItem{item("Three"), item("Four")}}),
+				item("Five"),
+			}},
+			&OrderedList{Properties: []Property{{Name: "class", Value: "sub"}}, Start: 1, ListItems: []ListItem{item("Six")}},
+		),
+		item("Seven"),
+	}}
+
+	if !reflect.DeepEqual(elements[0], expected) {
+		fail(t, fmt.Sprintf("Expected %s, got=%s", expected.Raw(), elements[0].Raw()))
+	}
+
+	if _, ok := elements[1].(*Paragraph); !ok {
+		fail(t, fmt.Sprintf("Expected Paragraph after list, got=%T", elements[1]))
+	}
+}
+
+func TestParseListItemInlineContent(t *testing.T) {
+	input := "- Some **bold** and `code` with [a link](/x)\n- plain  "
+	elements := clearRanges(execute(t, input))
+	validateLength(t, len(elements), 1)
+
+	expected := &UnorderedList{ListItems: []ListItem{
+		{Component: &Paragraph{Content: []Node{
+			&Fragment{Value: "Some "},
+			&Bold{Content: []Node{&Fragment{Value: "bold"}}},
+			&Fragment{Value: " and "},
+			&Code{Text: "code"},
+			&Fragment{Value: " with "},
+			&Link{Url: "/x", Content: []Node{&Fragment{Value: "a link"}}},
+		}}},
+		{Component: &Paragraph{Content: []Node{&Fragment{Value: "plain"}}}},
+	}}
+
+	if !reflect.DeepEqual(elements[0], expected) {
+		fail(t, fmt.Sprintf("Expected %s, got=%s", expected.Raw(), elements[0].Raw()))
+	}
+}
+
+func TestParseListItemBlocks(t *testing.T) {
+	input := "- First\n\n  Second paragraph\n  continued\n  ^^\n  code\n  ^^\n  > quoted\n- Next\n\n- Last\n\nAfter"
+	elements := clearRanges(execute(t, input))
+	validateLength(t, len(elements), 2)
+
+	list, ok := elements[0].(*UnorderedList)
+	if !ok {
+		fail(t, fmt.Sprintf("Expected UnorderedList, got=%T", elements[0]))
+		t.FailNow()
+	}
+	validateLength(t, len(list.ListItems), 3)
+
+	expected := []Node{
+		&Paragraph{Content: []Node{&Fragment{Value: "Second paragraph continued"}}},
+		&CodeBlock{Content: "code"},
+		&BlockQuote{Content: []Node{&Fragment{Value: "quoted"}}},
+	}
+	if !reflect.DeepEqual(list.ListItems[0].Children, expected) {
+		fail(t, fmt.Sprintf("Expected %s, got=%s", rawString(expected...), rawString(list.ListItems[0].Children...)))
+	}
+
+	if _, ok := elements[1].(*Paragraph); !ok {
+		fail(t, fmt.Sprintf("Expected Paragraph after list, got=%T", elements[1]))
+	}
+}
+
+func TestParseParagraphEndsBeforeIndentedList(t *testing.T) {
+	input := "Paragraph\n  - item\n\n> quote\n- item"
+	elements := execute(t, input)
+	validateLength(t, len(elements), 4)
+
+	for i, expected := range []string{"*mdx.Paragraph", "*mdx.UnorderedList", "*mdx.BlockQuote", "*mdx.UnorderedList"} {
+		if actual := fmt.Sprintf("%T", elements[i]); actual != expected {
+			fail(t, fmt.Sprintf("Expected %s at %d, got=%s", expected, i, actual))
+		}
+	}
+}
+
+func TestParseTaskLists(t *testing.T) {
+	input := "- [ ] Todo\n- [x] Done\n- [X] { .enabled .name=release } **Ship** it\n- Plain\n\n1. [x] Ordered"
+	elements := clearRanges(execute(t, input))
+	validateLength(t, len(elements), 2)
+
+	paragraph := func(nodes ...Node) *Paragraph {
+		return &Paragraph{Content: nodes}
+	}
+	expected := &UnorderedList{ListItems: []ListItem{
+		{Component: paragraph(&Fragment{Value: "Todo"}), Task: true},
+		{Component: paragraph(&Fragment{Value: "Done"}), Task: true, Checked: true},
+		{
+			Component:          paragraph(&Bold{Content: []Node{&Fragment{Value: "Ship"}}}, &Fragment{Value: " it"}),
+			Task:               true,
+			Checked:            true,
+			CheckboxProperties: []Property{{Name: "enabled"}, {Name: "name", Value: "release"}},
+		},
+		{Component: paragraph(&Fragment{Value: "Plain"})},
+	}}
+
+	if !reflect.DeepEqual(elements[0], expected) {
+		fail(t, fmt.Sprintf("Expected %s, got=%s", expected.Raw(), elements[0].Raw()))
+	}
+
+	ordered, ok := elements[1].(*OrderedList)
+	if !ok {
+		fail(t, fmt.Sprintf("Expected OrderedList, got=%T", elements[1]))
+	}
+	if ordered.ListItems[0].Task {
+		fail(t, "Expected ordered list items not to be tasks")
+	}
+}
+
+func TestParseTables(t *testing.T) {
+	input := "{ .class=prices }\n| Fruit | **Price** | Note |\n| :--- | ---: | :-: |\n| { .class=name } Apple | 1 | `a|b` \\| c |\n| Pear | 2 | { .class=total }\n| Fig |\n\nAfter"
+	elements := clearRanges(execute(t, input))
+	validateLength(t, len(elements), 2)
+
+	text := func(value string) []Node {
+		return []Node{&Fragment{Value: value}}
+	}
+	alignments := []Alignment{AlignLeft, AlignRight, AlignCenter}
+	row := func(properties []Property, header bool, cells ...TableCell) TableRow {
+		for i := range cells {
+			cells[i].Header = header
+			cells[i].Align = alignments[i]
+		}
+		return TableRow{Properties: properties, Cells: cells}
+	}
+
+	expected := &Table{
+		Properties: []Property{{Name: "class", Value: "prices"}},
+		Header: row(nil, true,
+			TableCell{Content: text("Fruit")},
+			TableCell{Content: []Node{&Bold{Content: text("Price")}}},
+			TableCell{Content: text("Note")},
+		),
+		Rows: []TableRow{
+			row(nil, false,
+				TableCell{Properties: []Property{{Name: "class", Value: "name"}}, Content: text("Apple")},
+				TableCell{Content: text("1")},
+				TableCell{Content: []Node{&Code{Text: "a|b"}, &Fragment{Value: " | c"}}},
+			),
+			row([]Property{{Name: "class", Value: "total"}}, false,
+				TableCell{Content: text("Pear")},
+				TableCell{Content: text("2")},
+				TableCell{Content: []Node{}},
+			),
+			row(nil, false,
+				TableCell{Content: text("Fig")},
+				TableCell{Content: []Node{}},
+				TableCell{Content: []Node{}},
+			),
+		},
+	}
+
+	if !reflect.DeepEqual(elements[0], expected) {
+		fail(t, fmt.Sprintf("Expected %s, got=%s", expected.Raw(), elements[0].Raw()))
+	}
+
+	if _, ok := elements[1].(*Paragraph); !ok {
+		fail(t, fmt.Sprintf("Expected Paragraph after table, got=%T", elements[1]))
+	}
+}
+
+func TestParseTableRowAfterUnclosedSpan(t *testing.T) {
+	elements := execute(t, "| a | b |\n| - | - |\n| c | $d |\n| e | f |")
+	validateLength(t, len(elements), 1)
+
+	table, ok := elements[0].(*Table)
+	if !ok {
+		fail(t, fmt.Sprintf("Expected Table, got=%T", elements[0]))
+	}
+	if len(table.Rows) != 2 {
+		fail(t, fmt.Sprintf("Expected 2 rows, got=%s", table.Raw()))
+	}
+}
+
+func TestParsePipeInPropertyValue(t *testing.T) {
+	elements := clearRanges(execute(t, "{ .title=a|b }\n# Heading"))
+	expected := []Node{&Heading{Level: 1, Properties: []Property{{Name: "title", Value: "a|b"}}, Content: []Node{&Fragment{Value: "Heading"}}}}
+
+	if !reflect.DeepEqual(elements, expected) {
+		fail(t, fmt.Sprintf("Expected=%#v, got=%#v", expected, elements))
+	}
+}
+
+func TestParseTableRequiresDelimiterRow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"| a | b |\n| c | d |", "<p>| a | b | | c | d |</p>"},
+		{"| a |\n| -x- |", "<p>| a | | -x- |</p>"},
+		{"a | b\n| - |", "<p>a | b | - |</p>"},
+	}
+
+	for _, test := range tests {
+		elements := execute(t, test.input)
+		validateLength(t, len(elements), 1)
+		if actual := elements[0].Raw(); actual != test.expected {
+			fail(t, fmt.Sprintf("Expected %q, got=%q", test.expected, actual))
+		}
+	}
+}
+
+func TestParseTableEndsParagraph(t *testing.T) {
+	elements := execute(t, "Intro\n| a |\n| - |\n| b |\nOutro")
+	validateLength(t, len(elements), 3)
+
+	table, ok := elements[1].(*Table)
+	if !ok {
+		fail(t, fmt.Sprintf("Expected Table, got=%T", elements[1]))
+	}
+	if len(table.Rows) != 1 {
+		fail(t, fmt.Sprintf("Expected 1 row, got=%d", len(table.Rows)))
+	}
+
+	expected := SourceRange{Start: Position{Offset: 6, Line: 2, Column: 1}, End: Position{Offset: 23, Line: 4, Column: 6}}
+	if table.Range() != expected {
+		fail(t, fmt.Sprintf("Expected range %v, got=%v", expected, table.Range()))
+	}
+}
+
+func TestParseIndentedListInDiv(t *testing.T) {
+	input := "[\n\t- First\n\t- Second\n\t\t- Nested\n]"
+	elements := execute(t, input)
+	validateLength(t, len(elements), 1)
+
+	div := elements[0].(*Div)
+	validateLength(t, len(div.Children), 1)
+	list, ok := div.Children[0].(*UnorderedList)
+	if !ok {
+		fail(t, fmt.Sprintf("Expected UnorderedList, got=%T", div.Children[0]))
+		t.FailNow()
+	}
+
+	validateLength(t, len(list.ListItems), 2)
+	validateLength(t, len(list.ListItems[1].Children), 1)
+}
+
+func TestParseNestedListRanges(t *testing.T) {
+	input := "- a\n  - b\n- c"
+	elements := execute(t, input)
+	validateLength(t, len(elements), 1)
+
+	list := elements[0].(*UnorderedList)
+	first := list.ListItems[0]
+	nested := first.Children[0].(*UnorderedList)
+
+	expectedItem := SourceRange{Start: Position{Offset: 0, Line: 1, Column: 1}, End: Position{Offset: 9, Line: 2, Column: 6}}
+	if first.Range() != expectedItem {
+		fail(t, fmt.Sprintf("Expected item range %+v, got=%+v", expectedItem, first.Range()))
+	}
+
+	expectedNested := SourceRange{Start: Position{Offset: 6, Line: 2, Column: 3}, End: Position{Offset: 9, Line: 2, Column: 6}}
+	if nested.Range() != expectedNested {
+		fail(t, fmt.Sprintf("Expected nested list range %+v, got=%+v", expectedNested, nested.Range()))
+	}
+}
+
+func TestListMarkerAt(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected listMarker
+		ok       bool
+	}{
+		{"- a", listMarker{}, true},
+		{"  - a", listMarker{indent: 2}, true},
+		{"\t12. a", listMarker{indent: 4, ordered: true}, true},
+		{"  \t- a", listMarker{indent: 4}, true},
+		{"  { .a=\"}\" }\n\n    1. a", listMarker{indent: 4, ordered: true, hasProperties: true}, true},
+		{"{ .a=b } - a", listMarker{}, false},
+		{"-a", listMarker{}, false},
+		{"  text", listMarker{}, false},
+	}
+
+	for _, test := range tests {
+		marker, ok := listMarkerAt(test.input, 0)
+		if ok != test.ok || (ok && marker != test.expected) {
+			fail(t, fmt.Sprintf("Expected %+v %t for %q, got=%+v %t", test.expected, test.ok, test.input, marker, ok))
+		}
+	}
+}
+
 func TestParseOrderedListBetweenElements(t *testing.T) {
 	input := `# Header
 1. First
@@ -712,7 +1179,7 @@ func TestParseOrderedListBetweenElements(t *testing.T) {
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if list, ok := element.(*orderedList); ok {
+	if list, ok := element.(*OrderedList); ok {
 		validateLength(t, len(list.ListItems), 2)
 	} else {
 		fail(t, fmt.Sprintf("Expected OrderedList, got=%T", element))
@@ -727,14 +1194,14 @@ func TestParseUnorderedList(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if list, ok := element.(*unorderedList); ok {
+	if list, ok := element.(*UnorderedList); ok {
 		validateLength(t, len(list.ListItems), 2)
 
 		first := list.ListItems[0]
-		if p, ok := first.Component.(*paragraph); ok {
+		if p, ok := first.Component.(*Paragraph); ok {
 			validateLength(t, len(p.Content), 1)
 
-			if pText, ok := p.Content[0].(*fragment); ok {
+			if pText, ok := p.Content[0].(*Fragment); ok {
 				if pText.Value != "First" {
 					fail(t, fmt.Sprintf("Expected item text='First', got='%s'", pText.Value))
 				}
@@ -746,10 +1213,10 @@ func TestParseUnorderedList(t *testing.T) {
 		}
 
 		second := list.ListItems[1]
-		if p, ok := second.Component.(*paragraph); ok {
+		if p, ok := second.Component.(*Paragraph); ok {
 			validateLength(t, len(p.Content), 1)
 
-			if pText, ok := p.Content[0].(*fragment); ok {
+			if pText, ok := p.Content[0].(*Fragment); ok {
 				if pText.Value != "Second" {
 					fail(t, fmt.Sprintf("Expected item text='Second', got='%s'", pText.Value))
 				}
@@ -773,7 +1240,7 @@ func TestParseUnorderedListBetweenElements(t *testing.T) {
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if list, ok := element.(*unorderedList); ok {
+	if list, ok := element.(*UnorderedList); ok {
 		validateLength(t, len(list.ListItems), 2)
 	} else {
 		fail(t, fmt.Sprintf("Expected UnorderedList, got=%T", element))
@@ -786,7 +1253,7 @@ func TesParseImage(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if img, ok := element.(*image); ok {
+	if img, ok := element.(*Image); ok {
 		if img.AltText != "Image" {
 			fail(t, fmt.Sprintf("Expected Image AltText='Image', got='%s'", img.AltText))
 		}
@@ -807,7 +1274,7 @@ Hello`
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if img, ok := element.(*image); ok {
+	if img, ok := element.(*Image); ok {
 		if img.AltText != "Image" {
 			fail(t, fmt.Sprintf("Expected Image text='Image', got='%s'", img.AltText))
 		}
@@ -826,7 +1293,7 @@ func TestParseHorizontalRule(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if _, ok := element.(*horizontalRule); !ok {
+	if _, ok := element.(*HorizontalRule); !ok {
 		fail(t, fmt.Sprintf("Expected HorizontalRule, got=%T", element))
 	}
 
@@ -835,7 +1302,7 @@ func TestParseHorizontalRule(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element = elements[0]
 
-	if _, ok := element.(*horizontalRule); !ok {
+	if _, ok := element.(*HorizontalRule); !ok {
 		fail(t, fmt.Sprintf("Expected HorizontalRule, got=%T", element))
 	}
 }
@@ -848,7 +1315,7 @@ Hello`
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if _, ok := element.(*horizontalRule); !ok {
+	if _, ok := element.(*HorizontalRule); !ok {
 		fail(t, fmt.Sprintf("Expected HorizontalRule, got=%T", element))
 	}
 
@@ -859,7 +1326,7 @@ Hello`
 	validateLength(t, len(elements), 3)
 	element = elements[1]
 
-	if _, ok := element.(*horizontalRule); !ok {
+	if _, ok := element.(*HorizontalRule); !ok {
 		fail(t, fmt.Sprintf("Expected HorizontalRule, got=%T", element))
 	}
 }
@@ -870,7 +1337,7 @@ func TestParseLink(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if link, ok := element.(*link); ok {
+	if link, ok := element.(*Link); ok {
 		if link.InnerHtml() != "Text" {
 			fail(t, fmt.Sprintf("Expected Link text='Text', got='%s'", link.InnerHtml()))
 		}
@@ -887,7 +1354,7 @@ func TestParseLink(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element = elements[0]
 
-	if link, ok := element.(*link); ok {
+	if link, ok := element.(*Link); ok {
 		if link.InnerHtml() != "https://linkurl.com" {
 			fail(t, fmt.Sprintf("Expected Link text='https://linkurl.com', got='%s'", link.InnerHtml()))
 		}
@@ -908,7 +1375,7 @@ func TestParseLinkBetweenElements(t *testing.T) {
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if link, ok := element.(*link); ok {
+	if link, ok := element.(*Link); ok {
 		if link.InnerHtml() != "Link" {
 			fail(t, fmt.Sprintf("Expected Link text='Link', got='%s'", link.InnerHtml()))
 		}
@@ -928,7 +1395,7 @@ func TestParseLinkBetweenElements(t *testing.T) {
 	validateLength(t, len(elements), 3)
 	element = elements[1]
 
-	if link, ok := element.(*link); ok {
+	if link, ok := element.(*Link); ok {
 		if link.InnerHtml() != "https://linkurl.com" {
 			fail(t, fmt.Sprintf("Expected Link text='https://linkurl.com', got='%s'", link.InnerHtml()))
 		}
@@ -947,13 +1414,13 @@ func TestParseLinkWithNestedElements(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if link, ok := element.(*link); ok {
+	if link, ok := element.(*Link); ok {
 		validateLength(t, len(link.Content), 1)
-		if div, ok := link.Content[0].(*div); ok {
+		if div, ok := link.Content[0].(*Div); ok {
 			validateLength(t, len(div.Children), 1)
-			if p, ok := div.Children[0].(*paragraph); ok {
+			if p, ok := div.Children[0].(*Paragraph); ok {
 				validateLength(t, len(p.Content), 1)
-				if frag, ok := p.Content[0].(*fragment); ok {
+				if frag, ok := p.Content[0].(*Fragment); ok {
 					if frag.Value != "Link" {
 						fail(t, fmt.Sprintf("Expected Fragment text='Link', got='%s'", frag.Value))
 					}
@@ -981,16 +1448,16 @@ func TestParseButton(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if button, ok := element.(*button); ok {
+	if button, ok := element.(*Button); ok {
 		if button.OnClick != "handleClick" {
 			fail(t, fmt.Sprintf("Expected OnClick='handleClick', got='%s'", button.OnClick))
 		}
 
 		validateLength(t, len(button.Content), 1)
 
-		if p, ok := button.Content[0].(*paragraph); ok {
+		if p, ok := button.Content[0].(*Paragraph); ok {
 			validateLength(t, len(p.Content), 1)
-			if frag, ok := p.Content[0].(*fragment); ok {
+			if frag, ok := p.Content[0].(*Fragment); ok {
 				if frag.Value != "Click Me" {
 					fail(t, fmt.Sprintf("Expected Fragment text='Click Me', got='%s'", frag.Value))
 				}
@@ -1011,11 +1478,11 @@ func TestParseButtonBetweenElements(t *testing.T) {
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if button, ok := element.(*button); ok {
+	if button, ok := element.(*Button); ok {
 		validateLength(t, len(button.Content), 1)
-		if p, ok := button.Content[0].(*paragraph); ok {
+		if p, ok := button.Content[0].(*Paragraph); ok {
 			validateLength(t, len(p.Content), 1)
-			if frag, ok := p.Content[0].(*fragment); ok {
+			if frag, ok := p.Content[0].(*Fragment); ok {
 				if frag.Value != "Click Me" {
 					fail(t, fmt.Sprintf("Expected Paragraph text='Click Me', got='%s'", frag.Value))
 				}
@@ -1036,17 +1503,17 @@ func TestParseButtonWithNestedElements(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if button, ok := element.(*button); ok {
+	if button, ok := element.(*Button); ok {
 		if button.OnClick != "handleClick" {
 			fail(t, fmt.Sprintf("Expected OnClick='handleClick', got='%s'", button.OnClick))
 		}
 
 		validateLength(t, len(button.Content), 1)
-		if div, ok := button.Content[0].(*div); ok {
+		if div, ok := button.Content[0].(*Div); ok {
 			validateLength(t, len(div.Children), 1)
-			if p, ok := div.Children[0].(*paragraph); ok {
+			if p, ok := div.Children[0].(*Paragraph); ok {
 				validateLength(t, len(p.Content), 1)
-				if frag, ok := p.Content[0].(*fragment); ok {
+				if frag, ok := p.Content[0].(*Fragment); ok {
 					if frag.Value != "Click Me" {
 						fail(t, fmt.Sprintf("Expected fragment value='Click Me', got='%s'", frag.Value))
 					}
@@ -1072,7 +1539,7 @@ func TestParseDiv(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if div, ok := element.(*div); ok {
+	if div, ok := element.(*Div); ok {
 		validateLength(t, len(div.Children), 1)
 	} else {
 		fail(t, fmt.Sprintf("Expected Div type, got=%T", element))
@@ -1087,7 +1554,7 @@ func TestParseDiv(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element = elements[0]
 
-	if div, ok := element.(*div); ok {
+	if div, ok := element.(*Div); ok {
 		validateLength(t, len(div.Children), 1)
 	} else {
 		fail(t, fmt.Sprintf("Expected Div type, got=%T", element))
@@ -1102,14 +1569,14 @@ func TestParseNav(t *testing.T) {
 
 	element := elements[0]
 
-	if nav, ok := element.(*nav); ok {
+	if nav, ok := element.(*Nav); ok {
 		validateLength(t, len(nav.Children), 1)
 
 		child := nav.Children[0]
-		if p, ok := child.(*paragraph); ok {
+		if p, ok := child.(*Paragraph); ok {
 			validateLength(t, len(p.Content), 1)
 
-			if frag, ok := p.Content[0].(*fragment); ok {
+			if frag, ok := p.Content[0].(*Fragment); ok {
 				if frag.Value != "Navigate" {
 					fail(t, fmt.Sprintf("Expected Nav text='Navigate', got='%s'", frag.Value))
 				}
@@ -1132,11 +1599,11 @@ func TestParseNavBetweenElements(t *testing.T) {
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if nav, ok := element.(*nav); ok {
+	if nav, ok := element.(*Nav); ok {
 		validateLength(t, len(nav.Children), 1)
-		if p, ok := nav.Children[0].(*paragraph); ok {
+		if p, ok := nav.Children[0].(*Paragraph); ok {
 			validateLength(t, len(p.Content), 1)
-			if frag, ok := p.Content[0].(*fragment); ok {
+			if frag, ok := p.Content[0].(*Fragment); ok {
 				if frag.Value != "Nav" {
 					fail(t, fmt.Sprintf("Expected fragment text='Nav', got=''%s'", frag.Value))
 				}
@@ -1163,15 +1630,15 @@ func TestParseNavWithNestedElements(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if nav, ok := element.(*nav); ok {
+	if nav, ok := element.(*Nav); ok {
 		validateLength(t, len(nav.Children), 2)
-		if div1, ok := nav.Children[0].(*div); ok {
+		if div1, ok := nav.Children[0].(*Div); ok {
 			validateLength(t, len(div1.Children), 1)
 		} else {
 			fail(t, fmt.Sprintf("Expected Div, got=%T", nav.Children[0]))
 		}
 
-		if div2, ok := nav.Children[1].(*div); ok {
+		if div2, ok := nav.Children[1].(*Div); ok {
 			validateLength(t, len(div2.Children), 1)
 		} else {
 			fail(t, fmt.Sprintf("Expected Div, got=%T", nav.Children[1]))
@@ -1187,11 +1654,11 @@ func TestParseSpan(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if span, ok := element.(*span); ok {
+	if span, ok := element.(*Span); ok {
 		validateLength(t, len(span.Content), 1)
 
 		child := span.Content[0]
-		if frag, ok := child.(*fragment); ok {
+		if frag, ok := child.(*Fragment); ok {
 			if frag.Value != "span" {
 				fail(t, fmt.Sprintf("Expected text='span', got='%s'", frag.Value))
 			}
@@ -1211,9 +1678,9 @@ $ span $
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if span, ok := element.(*span); ok {
+	if span, ok := element.(*Span); ok {
 		validateLength(t, len(span.Content), 1)
-		if frag, ok := span.Content[0].(*fragment); ok {
+		if frag, ok := span.Content[0].(*Fragment); ok {
 			if frag.Value != "span" {
 				fail(t, fmt.Sprintf("Expected span text='span', got='%s'", frag.Value))
 			}
@@ -1231,21 +1698,21 @@ func TestParseSpanWithNestedElements(t *testing.T) {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if span, ok := element.(*span); ok {
+	if span, ok := element.(*Span); ok {
 		validateLength(t, len(span.Content), 3)
-		if frag1, ok := span.Content[0].(*fragment); ok {
+		if frag1, ok := span.Content[0].(*Fragment); ok {
 			if frag1.Value != "Hello, " {
 				fail(t, fmt.Sprintf("Expected Fragment text='Hello, ', got='%s'", frag1.Value))
 			}
 		}
 
-		if code, ok := span.Content[1].(*code); ok {
+		if code, ok := span.Content[1].(*Code); ok {
 			if code.Text != "world" {
 				fail(t, fmt.Sprintf("Expected Fragment text='world', got='%s'", code.Text))
 			}
 		}
 
-		if frag2, ok := span.Content[2].(*fragment); ok {
+		if frag2, ok := span.Content[2].(*Fragment); ok {
 			if frag2.Value != "!" {
 				fail(t, fmt.Sprintf("Expected Fragment text='!', got='%s'", frag2.Value))
 			}
@@ -1265,8 +1732,8 @@ func main() {
 	validateLength(t, len(elements), 1)
 	element := elements[0]
 
-	if codeBlock, ok := element.(*codeBlock); ok {
-		expectedCode := "func main() {\\n    fmt.Println(\"Hello, world!\")\\n}"
+	if codeBlock, ok := element.(*CodeBlock); ok {
+		expectedCode := "func main() {\n    fmt.Println(\"Hello, world!\")\n}"
 		if codeBlock.Content != expectedCode {
 			fail(t, fmt.Sprintf("Expected content='%s', got='%s'", expectedCode, codeBlock.Content))
 		}
@@ -1287,8 +1754,8 @@ func main() {
 	validateLength(t, len(elements), 3)
 	element := elements[1]
 
-	if codeBlock, ok := element.(*codeBlock); ok {
-		expectedCode := "func main() {\\n    fmt.Println(\"Hello, world!\")\\n}"
+	if codeBlock, ok := element.(*CodeBlock); ok {
+		expectedCode := "func main() {\n    fmt.Println(\"Hello, world!\")\n}"
 		if codeBlock.Content != expectedCode {
 			fail(t, fmt.Sprintf("Expected content='%s', got='%s'", expectedCode, codeBlock.Content))
 		}
@@ -1297,33 +1764,178 @@ func main() {
 	}
 }
 
+func TestParseCodeBlockLanguage(t *testing.T) {
+	input := "{ .class=wide .LANG=Go }\n^^\nx := 1\n^^\n^^\ny\n^^"
+	elements := clearRanges(execute(t, input))
+	expected := []Node{
+		&CodeBlock{Properties: []Property{{Name: "class", Value: "wide"}}, Language: "Go", Content: "x := 1"},
+		&CodeBlock{Content: "y"},
+	}
+
+	if !reflect.DeepEqual(elements, expected) {
+		fail(t, fmt.Sprintf("Expected=%#v, got=%#v", expected, elements))
+	}
+}
+
+func TestParseCodeBlockLineOptions(t *testing.T) {
+	input := "{ .lines=true .start=40 .highlight=\"41-42, 45\" .title=main.go .class=wide }\n^^\nx\n^^\n{ .lines }\n^^\ny\n^^"
+	elements := clearRanges(execute(t, input))
+	expected := []Node{
+		&CodeBlock{
+			Properties:     []Property{{Name: "class", Value: "wide"}},
+			LineNumbers:    true,
+			StartLine:      40,
+			HighlightLines: []LineRange{{Start: 41, End: 42}, {Start: 45, End: 45}},
+			Title:          "main.go",
+			Content:        "x",
+		},
+		&CodeBlock{LineNumbers: true, Content: "y"},
+	}
+
+	if !reflect.DeepEqual(elements, expected) {
+		fail(t, fmt.Sprintf("Expected=%#v, got=%#v", expected, elements))
+	}
+}
+
+func TestParseCodeBlockEscapedLineBreak(t *testing.T) {
+	input := "{ .lines }\n^^\nfmt.Println(\"a\\nb\")\n\tfmt.Println(\"\\t\")\n^^"
+	elements := clearRanges(execute(t, input))
+	expected := []Node{&CodeBlock{LineNumbers: true, Content: "fmt.Println(\"a\\nb\")\n    fmt.Println(\"\\t\")"}}
+
+	if !reflect.DeepEqual(elements, expected) {
+		fail(t, fmt.Sprintf("Expected=%#v, got=%#v", expected, elements))
+	}
+
+	// the escaped line break is part of the first line rather than starting another
+	expectedHtml := "<div class=\"code-block\">\n" +
+		"    <pre><span class=\"line-number\">1</span>fmt.Println(\"a\\nb\")</pre>\n" +
+		"    <pre><span class=\"line-number\">2</span>    fmt.Println(\"\\t\")</pre>\n</div>"
+	if actual := elements[0].Raw(); actual != expectedHtml {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expectedHtml, actual))
+	}
+}
+
+func TestParseCodeBlockInvalidLineOptions(t *testing.T) {
+	document, err := Parse([]byte("# Code\n{ .lines=yes .start=0 .highlight=5-3 }\n^^\nx\n^^"))
+	if err != nil {
+		fail(t, err.Error())
+		t.FailNow()
+	}
+
+	expected := &CodeBlock{Content: "x"}
+	if actual := clearRanges(document.Children)[1]; !reflect.DeepEqual(actual, expected) {
+		fail(t, fmt.Sprintf("Expected=%#v, got=%#v", expected, actual))
+	}
+
+	expectedWarnings := []string{
+		"3:1: Invalid code block property lines=\"yes\", expected true or false",
+		"3:1: Invalid code block property start=\"0\", expected a line number",
+		"3:1: Invalid code block property highlight=\"5-3\", expected line numbers and ranges such as 3-5,9",
+	}
+	validateLength(t, len(document.Warnings), len(expectedWarnings))
+	for i, warning := range document.Warnings {
+		if warning.String() != expectedWarnings[i] {
+			fail(t, fmt.Sprintf("Expected %q, got=%q", expectedWarnings[i], warning.String()))
+		}
+	}
+}
+
+func TestParseFencedCodeBlocks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Node
+	}{
+		{"```go\nfunc main() {\n\n\tx := `a`\n}\n```", &FencedCodeBlock{Language: "go", Content: "func main() {\n\n\tx := `a`\n}"}},
+		{"~~~ js title=app.js\n```\nlet a\n~~~", &FencedCodeBlock{Language: "js", Content: "```\nlet a"}},
+		{"````\n```\n````", &FencedCodeBlock{Content: "```"}},
+		{"```\n```", &FencedCodeBlock{Content: ""}},
+		{"{ .class=example }\n~~~~sh\nls\n~~~~~  ", &FencedCodeBlock{Properties: []Property{{Name: "class", Value: "example"}}, Language: "sh", Content: "ls"}},
+		{"``` `x` ```", &Code{Text: "` `x` "}},
+		{"``double``", &Code{Text: "double"}},
+	}
+
+	for _, test := range tests {
+		elements := clearRanges(execute(t, test.input))
+		validateLength(t, len(elements), 1)
+		if !reflect.DeepEqual(elements[0], test.expected) {
+			fail(t, fmt.Sprintf("Expected %s for %q, got=%s", test.expected.Raw(), test.input, elements[0].Raw()))
+		}
+	}
+}
+
+func TestParseFencedCodeBlockBetweenElements(t *testing.T) {
+	input := "Intro\n  ```go\n  a\n    b\n  ```\nOutro"
+	elements := execute(t, input)
+	validateLength(t, len(elements), 3)
+
+	codeBlock, ok := elements[1].(*FencedCodeBlock)
+	if !ok {
+		fail(t, fmt.Sprintf("Expected FencedCodeBlock, got=%T", elements[1]))
+	}
+	if expected := "a\n  b"; codeBlock.Content != expected {
+		fail(t, fmt.Sprintf("Expected content=%q, got=%q", expected, codeBlock.Content))
+	}
+
+	expected := SourceRange{Start: Position{Offset: 8, Line: 2, Column: 3}, End: Position{Offset: 29, Line: 5, Column: 6}}
+	if codeBlock.Range() != expected {
+		fail(t, fmt.Sprintf("Expected range %v, got=%v", expected, codeBlock.Range()))
+	}
+}
+
+func TestParseUnclosedFence(t *testing.T) {
+	elements := execute(t, "```go\nfmt.Println()")
+	for _, element := range elements {
+		if _, ok := element.(*FencedCodeBlock); ok {
+			fail(t, "Expected unclosed fence not to be a code block")
+		}
+	}
+}
+
+func TestParseManyUnclosedFences(t *testing.T) {
+	// each fence would otherwise search the rest of the input for its closing fence
+	parseWithTimeout(t, strings.Repeat("```a\n", 20000))
+	parseWithTimeout(t, strings.Repeat("text `x`\n~~~a\n\n", 20000))
+	parseWithTimeout(t, strings.Repeat("`````a\n", 100)+strings.Repeat("````a\n", 20000))
+}
+
+func TestCodeFenceStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := newParser(newLexer("```\n" + strings.Repeat("x\n", 2*contextCheckInterval)))
+	p.ctx = ctx
+	if _, ok := p.codeFenceAt(0); ok || !errors.Is(p.err, context.Canceled) {
+		fail(t, fmt.Sprintf("Expected search for the closing fence to stop, got err=%v", p.err))
+	}
+}
+
 func TestParseBackslash(t *testing.T) {
-	inputs := map[string][]component{
+	inputs := map[string][]Node{
 		`\$0.69 is not enough for chicken nugget`: {
-			&paragraph{Content: []component{&fragment{Value: "$0.69 is not enough for chicken nugget"}}},
+			&Paragraph{Content: []Node{&Fragment{Value: "$0.69 is not enough for chicken nugget"}}},
 		},
 		`Having *italic maths 23\*3=69*`: {
-			&paragraph{Content: []component{
-				&fragment{Value: "Having "},
-				&italic{Content: []component{
-					&fragment{Value: "italic maths 23*3=69"},
+			&Paragraph{Content: []Node{
+				&Fragment{Value: "Having "},
+				&Italic{Content: []Node{
+					&Fragment{Value: "italic maths 23*3=69"},
 				}},
 			}},
 		},
 		`**Bold components cost \$4.20 \*jk**`: {
-			&bold{Content: []component{
-				&fragment{Value: "Bold components cost $4.20 *jk"},
+			&Bold{Content: []Node{
+				&Fragment{Value: "Bold components cost $4.20 *jk"},
 			}},
 		},
 		`> Quoted at \$4.20 each`: {
-			&blockQuote{Content: []component{
-				&fragment{Value: "Quoted at $4.20 each"},
+			&BlockQuote{Content: []Node{
+				&Fragment{Value: "Quoted at $4.20 each"},
 			}},
 		},
 	}
 
 	for test, expected := range inputs {
-		actual := execute(t, test)
+		actual := clearRanges(execute(t, test))
 		if !reflect.DeepEqual(actual, expected) {
 			fail(t, fmt.Sprintf("Expected %q, got=%q", expected, actual))
 		}
@@ -1332,20 +1944,286 @@ func TestParseBackslash(t *testing.T) {
 }
 
 func TestParser(t *testing.T) {
-	inputs := map[string][]component{
+	inputs := map[string][]Node{
 		"test\ntest": {
-			&paragraph{Content: []component{&fragment{Value: "test test"}}},
+			&Paragraph{Content: []Node{&Fragment{Value: "test test"}}},
 		},
 		"test\n\ntest": {
-			&paragraph{Content: []component{&fragment{Value: "test"}}},
-			&paragraph{Content: []component{&fragment{Value: "test"}}},
+			&Paragraph{Content: []Node{&Fragment{Value: "test"}}},
+			&Paragraph{Content: []Node{&Fragment{Value: "test"}}},
 		},
 	}
 
 	for test, expected := range inputs {
-		actual := execute(t, test)
+		actual := clearRanges(execute(t, test))
 		if !reflect.DeepEqual(actual, expected) {
 			fail(t, fmt.Sprintf("Expected %q, got=%q", expected, actual))
 		}
 	}
 }
+
+func TestParseRanges(t *testing.T) {
+	input := "# Title *em*\n\n> quote\n\n- one\n- two\n\n[\n  inner\n]\n\n^^\ncode\n^^"
+	elements := execute(t, input)
+	validateLength(t, len(elements), 5)
+
+	list := elements[2].(*UnorderedList)
+	div := elements[3].(*Div)
+	expected := map[Node]string{
+		elements[0]:                       "# Title *em*",
+		elements[0].(*Heading).Content[1]: "*em*",
+		elements[1]:                       "> quote",
+		list:                              "- one\n- two",
+		&list.ListItems[1]:                "- two",
+		list.ListItems[1].Component:       "two",
+		div:                               "[\n  inner\n]",
+		div.Children[0]:                   "inner",
+		elements[4]:                       "^^\ncode\n^^",
+	}
+
+	for node, text := range expected {
+		r := node.Range()
+		actual := input[r.Start.Offset:r.End.Offset]
+		if actual != text {
+			fail(t, fmt.Sprintf("Expected %T to span %q, got=%q (%s-%s)", node, text, actual, r.Start, r.End))
+		}
+	}
+
+	if r := div.Children[0].Range(); r.Start.Line != 9 || r.Start.Column != 3 {
+		fail(t, fmt.Sprintf("Expected range to start at 9:3, got=%s", r.Start))
+	}
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	input := "# Title\n\t{ .class test }\nHello"
+	_, err := newParser(newLexer(input)).parse(eof)
+
+	parseErr, ok := err.(*ParseError)
+	if !ok {
+		fail(t, fmt.Sprintf("Expected ParseError, got=%T", err))
+		t.FailNow()
+	}
+
+	expectedPos := Position{Offset: 17, Line: 2, Column: 10}
+	if parseErr.Pos != expectedPos {
+		fail(t, fmt.Sprintf("Expected position %+v, got=%+v", expectedPos, parseErr.Pos))
+	}
+
+	expected := "2:10: Property formatted incorrectly. KEY must be followed by EQUALS\n\t{ .class test }\n\t        ^"
+	if parseErr.Error() != expected {
+		fail(t, fmt.Sprintf("Expected error %q, got=%q", expected, parseErr.Error()))
+	}
+}
+
+func TestParseTerminates(t *testing.T) {
+	inputs := []string{
+		"{ .class=test",
+		"{ .class=test }",
+		"Hello\n// trailing comment",
+		"[\n{ .class=test",
+		"[\n{ .class }\n]",
+		"[link]({ .x=y",
+		"~[{ .class }](click)",
+		"@\n{ .class=\n@",
+		"> quote {",
+		"**bold {",
+		"$ span {",
+		"# heading {",
+	}
+
+	for _, input := range inputs {
+		parseWithTimeout(t, input)
+	}
+}
+
+func TestParseReturnsErrors(t *testing.T) {
+	inputs := []string{
+		"{ .class=test",
+		"[\n{ .class=test",
+		"[\n{ .class }\n]",
+		"[{ .class }](/url)",
+		"~[{ .class }](click)",
+		"@\n{ .class }\n@",
+		"{ .title=\"unterminated }\n# Heading",
+		"{ .title='unterminated\n}\n# Heading",
+		"{ # }\n# Heading",
+	}
+
+	for _, input := range inputs {
+		_, err := parseWithTimeout(t, input)
+		if _, ok := err.(*ParseError); !ok {
+			fail(t, fmt.Sprintf("Expected ParseError for %q, got=%v", input, err))
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"# Heading\nSome *italic* and **bold** text",
+		"{ .class=test }\n[\n  > quote\n  - one\n  - two\n]",
+		"1. first\n2. second\n\n---\n___",
+		"![alt](img.png) [link](/url) <https://example.com> ~[Click](handle)",
+		"@\n[Home](/home)\n@\n\n$ span $ `code` ``double``",
+		"^^\nfunc main() {}\n^^\n// comment",
+		"{ .class",
+		"\\$ escaped \\*",
+		"{ .style=\"a: b\" .title='c \\' d' }\n# e",
+		"- a\n  - b\n\t\t1. c\n  { .class=d }\n  2. e\n- f",
+		"- a **b** `c`\n\n  d\n  ^^\n  e\n  ^^\n  > f\n- g",
+		"- [ ] a\n- [x] { .enabled } b\n- [X]\n- []",
+		"{ .class=c }\n```go x\na\n\n\tb\n```\n~~~\n```\n~~~~\n``` `d`",
+		"{ .class=t }\n| a | **b** |\n| :- | -: |\n| { .class=c } d | `e|f` \\| |\n| g | { .class=h }",
+		"{ .lang=go .LANG=sql }\n^^\nSELECT 1\n^^",
+		"{ .lines .start=9 .highlight=1-3,10 .title=a.go }\n^^\nb\n\nc\n^^",
+		"{ .file=main.go .range=2-3 .region=a }\n^^ ^^",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		parseWithTimeout(t, input)
+	})
+}
+
+// Parses input, failing the test if parsing doesn't finish within a second.
+func parseWithTimeout(t *testing.T, input string) ([]Node, error) {
+	t.Helper()
+	type result struct {
+		nodes []Node
+		err   error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		nodes, err := newParser(newLexer(input)).parse(eof)
+		done <- result{nodes, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.nodes, r.err
+	case <-time.After(time.Second):
+		t.Fatalf("%s failed: parsing %q did not terminate", t.Name(), input)
+		return nil, nil
+	}
+}
+
+func TestParseStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := newParser(newLexer(strings.Repeat("Hello *world*\n\n", 1000)))
+	p.ctx = ctx
+	_, err := p.parse(eof)
+	if !errors.Is(err, context.Canceled) {
+		fail(t, fmt.Sprintf("Expected context.Canceled, got=%v", err))
+	}
+}
+
+func TestParseUnicode(t *testing.T) {
+	input := "\uFEFF# こんにちは 世界 🎉\n\n{ .title=Größe }\nÜber $ 日本語 *強調* $ 😀\n\n^^\nfmt.Println(\"héllo, 世界\")\n^^"
+	elements := execute(t, input)
+	validateLength(t, len(elements), 3)
+
+	heading, ok := elements[0].(*Heading)
+	if !ok || heading.Raw() != "<h1>こんにちは 世界 🎉</h1>" {
+		fail(t, fmt.Sprintf("Expected heading 'こんにちは 世界 🎉', got=%T %q", elements[0], elements[0].Raw()))
+	}
+
+	paragraph, ok := elements[1].(*Paragraph)
+	if !ok {
+		fail(t, fmt.Sprintf("Expected Paragraph, got=%T", elements[1]))
+		t.FailNow()
+	}
+
+	expectedProps := []Property{{Name: "title", Value: "Größe"}}
+	if !reflect.DeepEqual(paragraph.Properties, expectedProps) {
+		fail(t, fmt.Sprintf("Expected properties %v, got=%v", expectedProps, paragraph.Properties))
+	}
+
+	validateLength(t, len(paragraph.Content), 3)
+	span, ok := paragraph.Content[1].(*Span)
+	if !ok || span.Raw() != "<span>日本語 <em>強調</em></span>" {
+		fail(t, fmt.Sprintf("Expected span '日本語 <em>強調</em>', got=%T %q", paragraph.Content[1], paragraph.Content[1].Raw()))
+	}
+
+	codeBlock, ok := elements[2].(*CodeBlock)
+	if !ok || codeBlock.Content != "fmt.Println(\"héllo, 世界\")" {
+		fail(t, fmt.Sprintf("Expected code block content, got=%T %q", elements[2], elements[2].Raw()))
+	}
+
+	if r := span.Range(); r.Start.Column != 6 || input[r.Start.Offset:r.End.Offset] != "$ 日本語 *強調* $" {
+		fail(t, fmt.Sprintf("Expected span range from column 6, got=%s-%s", r.Start, r.End))
+	}
+}
+
+func TestParseErrorUnicodeColumn(t *testing.T) {
+	input := "# 日本語\n{ .名前= }\nHello"
+	_, err := newParser(newLexer(input)).parse(eof)
+
+	parseErr, ok := err.(*ParseError)
+	if !ok {
+		fail(t, fmt.Sprintf("Expected ParseError, got=%T", err))
+		t.FailNow()
+	}
+
+	expected := "2:7: Property formatted incorrectly. EQUALS must be followed by VALUE\n{ .名前= }\n      ^"
+	if parseErr.Error() != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected, parseErr.Error()))
+	}
+}
+
+func TestParseLineEndings(t *testing.T) {
+	inputs := []string{
+		"# Heading\nParagraph",
+		"First line\nsecond line\n\nNew paragraph",
+		"> quote\n> more\n>\n> after break\n>> nested\n\nAfter",
+		"- one\n- two\n\n1. first\n2. second\n\nAfter",
+		"[\n  # Title\n  Inside\n]\nAfter",
+		"@\n[Home](/home)\n[Feed](/feed)\n@",
+		"^^\nfunc main() {\n\tfmt.Println()\n}\n^^\nAfter",
+		"```go\nfunc main() {\n\n}\n```\nAfter",
+		"Before\n\n---\n\n___\nAfter",
+		"![alt](image.png)\nCaption",
+		"// comment\nVisible\n// trailing comment",
+		"{ .class=test }\n\n# Heading\n",
+		"*em* and **bold**\n`code` $ span $\n",
+	}
+
+	for _, input := range inputs {
+		expected := execute(t, input)
+		expectedHtml := (&Div{Children: expected}).Html(0)
+		expectedLine := expected[len(expected)-1].Range().Start.Line
+
+		for _, lineEnding := range []string{"\r\n", "\r"} {
+			converted := strings.ReplaceAll(input, "\n", lineEnding)
+			actual := execute(t, converted)
+			if len(actual) == 0 {
+				fail(t, fmt.Sprintf("Expected elements for %q", converted))
+				continue
+			}
+
+			actualHtml := (&Div{Children: actual}).Html(0)
+			if actualHtml != expectedHtml {
+				fail(t, fmt.Sprintf("Expected %q to produce %q, got=%q", converted, expectedHtml, actualHtml))
+			}
+
+			if line := actual[len(actual)-1].Range().Start.Line; line != expectedLine {
+				fail(t, fmt.Sprintf("Expected %q to end on line %d, got=%d", converted, expectedLine, line))
+			}
+		}
+	}
+}
+
+func TestParseErrorLineEndings(t *testing.T) {
+	for _, lineEnding := range []string{"\r\n", "\r"} {
+		input := "# Title" + lineEnding + "{ .class }" + lineEnding + "Hello"
+		_, err := newParser(newLexer(input)).parse(eof)
+
+		expected := "2:9: Property formatted incorrectly. KEY must be followed by EQUALS\n{ .class }\n        ^"
+		if err == nil || err.Error() != expected {
+			fail(t, fmt.Sprintf("Expected %q, got=%v", expected, err))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package mdx
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MergeProperties combines properties that share a name, compared case-insensitively as HTML attribute names are, so
+// that each name is written as a single attribute. Classes are concatenated without repeats and styles are joined with
+// "; ". For any other name the last value wins, and a warning is returned describing the value that was dropped.
+// Merged properties keep the position of the first property with that name.
+func MergeProperties(properties []Property) ([]Property, []string) {
+	merged := make([]Property, 0, len(properties))
+	indexes := make(map[string]int, len(properties))
+	var warnings []string
+
+	for _, property := range properties {
+		key := strings.ToLower(property.Name)
+		i, seen := indexes[key]
+		if !seen {
+			indexes[key] = len(merged)
+			merged = append(merged, property)
+			continue
+		}
+
+		existing := &merged[i]
+		switch key {
+		case "class":
+			existing.Value = joinClasses(existing.Value, property.Value)
+		case "style":
+			existing.Value = joinStyles(existing.Value, property.Value)
+		default:
+			warnings = append(warnings, fmt.Sprintf("Duplicate property %s, using %q instead of %q", property.Name, property.Value, existing.Value))
+			existing.Value = property.Value
+		}
+	}
+	return merged, warnings
+}
+
+// Returns the classes in a followed by any classes in b that a doesn't already have.
+func joinClasses(a, b string) string {
+	classes := strings.Fields(a)
+	for _, class := range strings.Fields(b) {
+		if !slices.Contains(classes, class) {
+			classes = append(classes, class)
+		}
+	}
+	return strings.Join(classes, " ")
+}
+
+// Returns the declarations in a followed by those in b, separated by a semicolon.
+func joinStyles(a, b string) string {
+	a = strings.TrimRight(strings.TrimSpace(a), ";")
+	b = strings.TrimRight(strings.TrimSpace(b), ";")
+	if len(a) == 0 || len(b) == 0 {
+		return a + b
+	}
+	return a + "; " + b
+}
@@ -0,0 +1,79 @@
+package mdx
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMergeProperties(t *testing.T) {
+	tests := []struct {
+		input            []Property
+		expected         []Property
+		expectedWarnings int
+	}{
+		{
+			[]Property{{Name: "class", Value: "a"}, {Name: "id", Value: "x"}, {Name: "class", Value: "b a"}},
+			[]Property{{Name: "class", Value: "a b"}, {Name: "id", Value: "x"}},
+			0,
+		},
+		{
+			[]Property{{Name: "style", Value: "color: red;"}, {Name: "Style", Value: "margin: 0"}, {Name: "style", Value: ""}},
+			[]Property{{Name: "style", Value: "color: red; margin: 0"}},
+			0,
+		},
+		{
+			[]Property{{Name: "title", Value: "first"}, {Name: "class", Value: "a"}, {Name: "TITLE", Value: "second"}},
+			[]Property{{Name: "title", Value: "second"}, {Name: "class", Value: "a"}},
+			1,
+		},
+		{
+			[]Property{{Name: "hidden"}, {Name: "data-x", Value: "1"}},
+			[]Property{{Name: "hidden"}, {Name: "data-x", Value: "1"}},
+			0,
+		},
+	}
+
+	for _, test := range tests {
+		actual, warnings := MergeProperties(test.input)
+		if !reflect.DeepEqual(actual, test.expected) {
+			fail(t, fmt.Sprintf("Expected %+v, got=%+v", test.expected, actual))
+		}
+
+		if len(warnings) != test.expectedWarnings {
+			fail(t, fmt.Sprintf("Expected %d warning(s), got=%q", test.expectedWarnings, warnings))
+		}
+	}
+}
+
+func TestRenderMergesProperties(t *testing.T) {
+	paragraph := &Paragraph{
+		Properties: []Property{{Name: "class", Value: "a"}, {Name: "style", Value: "color: red"}, {Name: "class", Value: "b"}, {Name: "style", Value: "margin: 0"}},
+		Content:    []Node{&Fragment{Value: "Hello"}},
+	}
+
+	expected := "<p class=\"a b\" style=\"color: red; margin: 0\">Hello</p>"
+	if actual := paragraph.Raw(); actual != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected, actual))
+	}
+}
+
+func TestParseDuplicatePropertyWarnings(t *testing.T) {
+	document, err := Parse([]byte("# Title\n{ .class=a .title=x .class=b .title=y }\nHello"))
+	if err != nil {
+		fail(t, err.Error())
+		t.FailNow()
+	}
+
+	paragraph := document.Children[1].(*Paragraph)
+	expected := []Property{{Name: "class", Value: "a b"}, {Name: "title", Value: "y"}}
+	if !reflect.DeepEqual(paragraph.Properties, expected) {
+		fail(t, fmt.Sprintf("Expected %+v, got=%+v", expected, paragraph.Properties))
+	}
+
+	validateLength(t, len(document.Warnings), 1)
+	expectedWarning := "2:1: Duplicate property title, using \"y\" instead of \"x\""
+	if document.Warnings[0].String() != expectedWarning {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expectedWarning, document.Warnings[0].String()))
+	}
+}
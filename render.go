@@ -0,0 +1,230 @@
+package mdx
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+)
+
+// Renderer converts each kind of node into output written to a RenderWriter.
+//
+// Each method is called with the writer positioned for that node. When w.Inline() is true the node is part of
+// inline content and should be written unformatted, otherwise it should be written formatted at w.IndentLevel().
+// Formatted output starts at the beginning of a line and ends with a line break. Block nodes write their own
+// indentation, while inline nodes follow the indentation written by their parent.
+// Child nodes must be rendered through w so that they are also passed to this Renderer.
+//
+// To override the output of a few node kinds, embed HtmlRenderer in a struct and define only those methods.
+type Renderer interface {
+	RenderFragment(w *RenderWriter, node *Fragment)
+	RenderLineBreak(w *RenderWriter, node *LineBreak)
+	RenderHeading(w *RenderWriter, node *Heading)
+	RenderParagraph(w *RenderWriter, node *Paragraph)
+	RenderCode(w *RenderWriter, node *Code)
+	RenderBold(w *RenderWriter, node *Bold)
+	RenderItalic(w *RenderWriter, node *Italic)
+	RenderBlockQuote(w *RenderWriter, node *BlockQuote)
+	RenderListItem(w *RenderWriter, node *ListItem)
+	RenderOrderedList(w *RenderWriter, node *OrderedList)
+	RenderUnorderedList(w *RenderWriter, node *UnorderedList)
+	RenderImage(w *RenderWriter, node *Image)
+	RenderHorizontalRule(w *RenderWriter, node *HorizontalRule)
+	RenderLink(w *RenderWriter, node *Link)
+	RenderButton(w *RenderWriter, node *Button)
+	RenderDiv(w *RenderWriter, node *Div)
+	RenderNav(w *RenderWriter, node *Nav)
+	RenderSpan(w *RenderWriter, node *Span)
+	RenderCodeBlock(w *RenderWriter, node *CodeBlock)
+	RenderFencedCodeBlock(w *RenderWriter, node *FencedCodeBlock)
+	RenderTable(w *RenderWriter, node *Table)
+	RenderTableRow(w *RenderWriter, node *TableRow)
+	RenderTableCell(w *RenderWriter, node *TableCell)
+}
+
+// Render writes the document to w as formatted HTML.
+// The output is identical to Document.Html, but is streamed rather than built up in memory.
+func Render(w io.Writer, doc *Document) error {
+	return RenderWith(w, doc, &HtmlRenderer{})
+}
+
+// RenderWith writes the document to w using renderer r.
+func RenderWith(w io.Writer, doc *Document, r Renderer) error {
+	_, err := renderHtml(context.Background(), w, &Div{Children: doc.Children}, 1, r)
+	return err
+}
+
+// Renders node as formatted output to w, starting on a new line so that it can follow other markup.
+// Writing stops once ctx is done.
+// Returns number of bytes written to w and the first write error encountered, or the context's error.
+func renderHtml(ctx context.Context, w io.Writer, node Node, indentLevel int, r Renderer) (int, error) {
+	hw := &htmlWriter{w: bufio.NewWriter(w), ctx: ctx}
+	rw := &RenderWriter{out: hw, renderer: r}
+	rw.WriteString("\n")
+	rw.Render(node, indentLevel)
+	return hw.Flush()
+}
+
+// RenderWriter is the destination passed to each Renderer method. It tracks how the current node is being
+// rendered and dispatches child nodes back to the Renderer.
+type RenderWriter struct {
+	out         io.StringWriter
+	renderer    Renderer
+	indentLevel int
+	inline      bool
+}
+
+func (w *RenderWriter) WriteString(s string) (int, error) {
+	return w.out.WriteString(s)
+}
+
+// IndentLevel returns the indent level the current node is being formatted at.
+func (w *RenderWriter) IndentLevel() int {
+	return w.indentLevel
+}
+
+// Inline reports whether the current node is being rendered unformatted as part of inline content.
+func (w *RenderWriter) Inline() bool {
+	return w.inline
+}
+
+// Render writes node formatted at the given indent level.
+func (w *RenderWriter) Render(node Node, indentLevel int) {
+	w.render(w.out, node, indentLevel, false)
+}
+
+// RenderInline writes each node unformatted.
+func (w *RenderWriter) RenderInline(nodes ...Node) {
+	for _, node := range nodes {
+		w.render(w.out, node, w.indentLevel, true)
+	}
+}
+
+// RenderString returns node formatted at the given indent level instead of writing it.
+func (w *RenderWriter) RenderString(node Node, indentLevel int) string {
+	var sb strings.Builder
+	w.render(&sb, node, indentLevel, false)
+	return sb.String()
+}
+
+// InlineString returns each node unformatted instead of writing them.
+func (w *RenderWriter) InlineString(nodes ...Node) string {
+	var sb strings.Builder
+	for _, node := range nodes {
+		w.render(&sb, node, w.indentLevel, true)
+	}
+	return sb.String()
+}
+
+func (w *RenderWriter) render(out io.StringWriter, node Node, indentLevel int, inline bool) {
+	prevOut, prevIndentLevel, prevInline := w.out, w.indentLevel, w.inline
+	w.out, w.indentLevel, w.inline = out, indentLevel, inline
+
+	r := w.renderer
+	switch n := node.(type) {
+	case *Fragment:
+		r.RenderFragment(w, n)
+	case *LineBreak:
+		r.RenderLineBreak(w, n)
+	case *Heading:
+		r.RenderHeading(w, n)
+	case *Paragraph:
+		r.RenderParagraph(w, n)
+	case *Code:
+		r.RenderCode(w, n)
+	case *Bold:
+		r.RenderBold(w, n)
+	case *Italic:
+		r.RenderItalic(w, n)
+	case *BlockQuote:
+		r.RenderBlockQuote(w, n)
+	case *ListItem:
+		r.RenderListItem(w, n)
+	case *OrderedList:
+		r.RenderOrderedList(w, n)
+	case *UnorderedList:
+		r.RenderUnorderedList(w, n)
+	case *Image:
+		r.RenderImage(w, n)
+	case *HorizontalRule:
+		r.RenderHorizontalRule(w, n)
+	case *Link:
+		r.RenderLink(w, n)
+	case *Button:
+		r.RenderButton(w, n)
+	case *Div:
+		r.RenderDiv(w, n)
+	case *Nav:
+		r.RenderNav(w, n)
+	case *Span:
+		r.RenderSpan(w, n)
+	case *CodeBlock:
+		r.RenderCodeBlock(w, n)
+	case *FencedCodeBlock:
+		r.RenderFencedCodeBlock(w, n)
+	case *Table:
+		r.RenderTable(w, n)
+	case *TableRow:
+		r.RenderTableRow(w, n)
+	case *TableCell:
+		r.RenderTableCell(w, n)
+	case *body:
+		renderBody(w, n)
+	default:
+		// node types defined outside this package render themselves
+		if inline {
+			w.WriteString(node.Raw())
+		} else {
+			w.WriteString(node.Html(indentLevel))
+		}
+	}
+
+	w.out, w.indentLevel, w.inline = prevOut, prevIndentLevel, prevInline
+}
+
+// Returns node rendered by the default HtmlRenderer, formatted at indentLevel.
+func htmlString(node Node, indentLevel int) string {
+	w := &RenderWriter{renderer: &HtmlRenderer{}}
+	return w.RenderString(node, indentLevel)
+}
+
+// Returns nodes rendered by the default HtmlRenderer as unformatted HTML.
+func rawString(nodes ...Node) string {
+	w := &RenderWriter{renderer: &HtmlRenderer{}}
+	return w.InlineString(nodes...)
+}
+
+// htmlWriter buffers formatted output on its way to the underlying writer, counting the bytes written.
+type htmlWriter struct {
+	w   *bufio.Writer
+	n   int
+	err error
+	// output is dropped once ctx is done, with the context's error kept as the writer's error
+	ctx context.Context
+}
+
+func (hw *htmlWriter) WriteString(s string) (int, error) {
+	if hw.err != nil || len(s) == 0 {
+		return 0, hw.err
+	}
+
+	select {
+	case <-hw.ctx.Done():
+		hw.err = hw.ctx.Err()
+		return 0, hw.err
+	default:
+	}
+
+	n, err := hw.w.WriteString(s)
+	hw.n += n
+	hw.err = err
+	return n, err
+}
+
+func (hw *htmlWriter) Flush() (int, error) {
+	if hw.err == nil {
+		hw.err = hw.w.Flush()
+	}
+
+	return hw.n, hw.err
+}
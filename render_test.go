@@ -0,0 +1,252 @@
+package mdx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Renders elements the way Transform did before streaming, by concatenating the Html of every element into one
+// string and collapsing line breaks over the whole document.
+func legacyHtml(elements []Node) string {
+	var html string
+	for _, element := range elements {
+		if element.Type() == Inline {
+			html += strings.Repeat(INDENT, 2)
+		}
+		html += element.Html(2)
+	}
+	return strings.ReplaceAll("\n"+INDENT+"<div>\n"+html+INDENT+"</div>\n", "\n\n", "\n")
+}
+
+func renderInputs(t testing.TB) map[string]string {
+	t.Helper()
+
+	inputs := map[string]string{
+		"heading":    "# Hello\nWorld",
+		"empty":      "",
+		"blankLines": "\n\n\n# Hi\n\n\n\nThere\n\n\n",
+		"nested":     "[\n\t# Title\n\t[\n\t\t## Subtitle\n\t]\n\t> Quote\n]\n@ [Home](/home) @",
+		"lists":      "1. One\n2. Two\n\n- A\n- B",
+		"button":     "~[**Click** me](handleClick) then $ span $",
+		"longLine":   strings.Repeat("lorem ipsum dolor sit amet ", 20),
+	}
+
+	for _, filename := range []string{"test.md", "spec.mdx", "examples/sample/sample.mdx", "examples/template/template.mdx"} {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[filename] = string(data)
+	}
+
+	return inputs
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", `
+    <div>
+    </div>
+`},
+		{"\n\n\n# Hi\n\n\n\nThere\n\n\n", `
+    <div>
+        <h1>Hi</h1>
+        <p>There</p>
+    </div>
+`},
+		{"[\n\t# Title\n\t[\n\t\t## Subtitle\n\t]\n\t> Quote\n]\n@ [Home](/home) @", `
+    <div>
+        <div>
+            <h1>Title</h1>
+            <div>
+                <h2>Subtitle</h2>
+            </div>
+            <blockquote>Quote</blockquote>
+        </div>
+        <nav>
+            <a href="/home" target=_blank>Home</a>
+        </nav>
+    </div>
+`},
+		{"1. One\n2. Two\n\n- A\n- B", `
+    <div>
+        <ol>
+            <li>
+                <p>One</p>
+            </li>
+            <li>
+                <p>Two</p>
+            </li>
+        </ol>
+        <ul>
+            <li>
+                <p>A</p>
+            </li>
+            <li>
+                <p>B</p>
+            </li>
+        </ul>
+    </div>
+`},
+		{"~[**Click** me](handleClick) then $ span $", `
+    <div>
+        <button onclick="handleClick(this)"><strong>Click</strong><p>me</p></button>
+        <p>then <span>span</span></p>
+    </div>
+`},
+		{strings.Repeat("lorem ipsum dolor sit amet ", 8), `
+    <div>
+        <p>
+            lorem ipsum dolor sit amet lorem ipsum dolor sit amet lorem ipsum dolor sit amet lorem ipsum dolor sit amet
+            lorem ipsum dolor sit amet lorem ipsum dolor sit amet lorem ipsum dolor sit amet lorem ipsum dolor sit amet
+        </p>
+    </div>
+`},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := Render(&buf, &Document{Children: execute(t, test.input)}); err != nil {
+			fail(t, err.Error())
+		}
+
+		if buf.String() != test.expected {
+			fail(t, fmt.Sprintf("%q: Render output differs\nexpected=%q\ngot=     %q", test.input, test.expected, buf.String()))
+		}
+	}
+}
+
+func TestRenderWritesNoBlankLines(t *testing.T) {
+	for name, input := range renderInputs(t) {
+		var buf bytes.Buffer
+		if err := Render(&buf, &Document{Children: execute(t, input)}); err != nil {
+			fail(t, err.Error())
+		}
+
+		if i := strings.Index(buf.String(), "\n\n"); i >= 0 {
+			fail(t, fmt.Sprintf("%s: Blank line in output at %d: %q", name, i, buf.String()[max(i-40, 0):i+2]))
+		}
+	}
+}
+
+const handbookSection = `{ .class=section-title }
+## Section heading
+
+This is a paragraph with **bold**, *italic* and ` + "`code`" + ` content, plus a [link](https://example.com) to
+somewhere else, which is long enough that it needs to be wrapped over more than one line when it is rendered.
+
+[
+	{ .class=callout }
+	> A quote inside a div
+
+	- First item
+	- Second item
+
+	1. Ordered item
+	2. Another ordered item
+]
+
+^^
+func main() {
+	fmt.Println("Hello, world!")
+}
+^^
+
+`
+
+type preRenderer struct {
+	HtmlRenderer
+}
+
+func (r *preRenderer) RenderCodeBlock(w *RenderWriter, node *CodeBlock) {
+	w.WriteString(strings.Repeat(INDENT, w.IndentLevel()) + "<pre><code>" + node.Content + "</code></pre>\n")
+}
+
+func (r *preRenderer) RenderButton(w *RenderWriter, node *Button) {
+	w.WriteString(strings.Repeat(INDENT, w.IndentLevel()) + "<a class=\"button\" href=\"#\">")
+	w.RenderInline(node.Content...)
+	w.WriteString("</a>\n")
+}
+
+func TestRenderWithOverrides(t *testing.T) {
+	input := `[
+	# Example
+^^
+x := 1
+^^
+	~[Click](handleClick)
+]`
+	document, err := Parse([]byte(input))
+	if err != nil {
+		fail(t, err.Error())
+		t.FailNow()
+	}
+
+	var buf bytes.Buffer
+	if err := RenderWith(&buf, document, &preRenderer{}); err != nil {
+		fail(t, err.Error())
+	}
+
+	expected := `
+    <div>
+        <div>
+            <h1>Example</h1>
+            <pre><code>x := 1</code></pre>
+            <a class="button" href="#"><p>Click</p></a>
+        </div>
+    </div>
+`
+	if buf.String() != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected, buf.String()))
+	}
+}
+
+// Parses a large, flat document resembling a long handbook page.
+func largeDocument(b *testing.B) *Document {
+	b.Helper()
+
+	document, err := Parse([]byte(strings.Repeat(handbookSection, 500)))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return document
+}
+
+func BenchmarkLegacyHtml(b *testing.B) {
+	document := largeDocument(b)
+	b.ResetTimer()
+
+	for range b.N {
+		io.WriteString(io.Discard, legacyHtml(document.Children))
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	document := largeDocument(b)
+	b.ResetTimer()
+
+	for range b.N {
+		Render(io.Discard, document)
+	}
+}
+
+func TestRenderStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	_, err := renderHtml(ctx, &buf, &Paragraph{Content: []Node{&Fragment{Value: "Hello"}}}, 1, &HtmlRenderer{})
+	if !errors.Is(err, context.Canceled) || buf.Len() > 0 {
+		fail(t, fmt.Sprintf("Expected context.Canceled and no output, got=%v %q", err, buf.String()))
+	}
+}
@@ -0,0 +1,101 @@
+package mdx
+
+import (
+	"strings"
+)
+
+// SafeOptions restricts what HtmlRenderer writes, so that MDX from untrusted authors can't run scripts or inject
+// arbitrary attributes into the page.
+type SafeOptions struct {
+	// Property names that may be written as attributes, where a trailing '*' matches any suffix as in "data-*".
+	// Nil uses DefaultAllowedAttributes.
+	AllowedAttributes []string
+	// URL schemes allowed in links, images and URL properties such as href. URLs without a scheme, such as /about or
+	// #top, are always allowed. Nil uses DefaultAllowedSchemes.
+	AllowedSchemes []string
+	// Click handlers that buttons may call, such as "handleClick" or "app.handleClick". Buttons calling any other
+	// handler, including all of them when this is empty, are written without an onclick.
+	AllowedHandlers []string
+	// Leave buttons out of the output entirely
+	DisableButtons bool
+}
+
+// DefaultAllowedAttributes are the property names written in safe mode when SafeOptions.AllowedAttributes is nil.
+var DefaultAllowedAttributes = []string{"class", "id", "title", "lang", "dir", "role", "width", "height", "hidden", "disabled", "aria-*", "data-*"}
+
+// DefaultAllowedSchemes are the URL schemes allowed in safe mode when SafeOptions.AllowedSchemes is nil.
+var DefaultAllowedSchemes = []string{"http", "https", "mailto"}
+
+// Attributes with values that browsers load or navigate to.
+var urlAttributes = []string{"href", "src", "action", "formaction", "poster", "cite", "background", "xlink:href"}
+
+func (o *SafeOptions) allowsAttribute(name string) bool {
+	allowed := o.AllowedAttributes
+	if allowed == nil {
+		allowed = DefaultAllowedAttributes
+	}
+
+	name = strings.ToLower(name)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether url is relative or uses one of the allowed schemes.
+func (o *SafeOptions) allowsUrl(url string) bool {
+	allowed := o.AllowedSchemes
+	if allowed == nil {
+		allowed = DefaultAllowedSchemes
+	}
+
+	// browsers skip leading whitespace and remove tabs and line breaks, so "java\tscript:" is still javascript:
+	url = strings.Map(func(ch rune) rune {
+		if ch == '\t' || ch == '\n' || ch == '\r' {
+			return -1
+		}
+		return ch
+	}, url)
+	url = strings.TrimLeftFunc(url, func(ch rune) bool {
+		return ch <= ' '
+	})
+
+	colon := strings.IndexByte(url, ':')
+	if colon < 0 || strings.ContainsAny(url[:colon], "/?#") {
+		return true
+	}
+
+	scheme := url[:colon]
+	for _, allowedScheme := range allowed {
+		if strings.EqualFold(scheme, allowedScheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUrlAttribute(name string) bool {
+	for _, attribute := range urlAttributes {
+		if strings.EqualFold(name, attribute) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether handler is one of the allowed handlers.
+func (o *SafeOptions) allowsHandler(handler string) bool {
+	for _, allowedHandler := range o.AllowedHandlers {
+		if handler == allowedHandler {
+			return true
+		}
+	}
+	return false
+}
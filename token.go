@@ -9,6 +9,20 @@ type tokenType string
 type token struct {
 	Type    tokenType
 	Literal string
+	Pos     Position
+	End     Position
+}
+
+// Position is a location in MDX source. Offset is counted in bytes from the start of the source, and Column in
+// runes from the start of the line. Line and Column start at 1.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 const (
@@ -36,13 +50,15 @@ const (
 	dot       = "."
 	slash     = "/"
 	at        = "@"
+	pipe      = "|"
 	backslash = "\\"
 	newline   = "\\n"
 	tab       = "\\t"
 
-	space = "SPACE"
-	word  = "WORD"
-	eof   = "EOF"
+	space  = "SPACE"
+	word   = "WORD"
+	quoted = "QUOTED"
+	eof    = "EOF"
 )
 
 func newToken(t tokenType, literal string) token {
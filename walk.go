@@ -0,0 +1,226 @@
+package mdx
+
+// WalkStatus tells Walk how to continue after visiting a node.
+type WalkStatus int
+
+const (
+	// Continue walking into the node's children and then its siblings
+	WalkContinue WalkStatus = iota
+	// Don't walk the node's children, but continue with its siblings
+	WalkSkipChildren
+	// Stop walking entirely
+	WalkStop
+)
+
+// Visitor receives every node visited by Walk.
+//
+// Enter is called before a node's children are walked, and Exit after. The node returned by either method replaces
+// the visited node in its parent, and returning nil removes it. A node removed by Enter is not walked any further.
+type Visitor interface {
+	Enter(node Node) (Node, WalkStatus)
+	Exit(node Node) (Node, WalkStatus)
+}
+
+// VisitorFunc adapts a function into a Visitor. It is called with entering set to true before a node's children
+// are walked, and false after.
+type VisitorFunc func(node Node, entering bool) (Node, WalkStatus)
+
+func (f VisitorFunc) Enter(node Node) (Node, WalkStatus) {
+	return f(node, true)
+}
+
+func (f VisitorFunc) Exit(node Node) (Node, WalkStatus) {
+	return f(node, false)
+}
+
+// Walk visits node and all of its descendants depth first.
+// Returns the node that should take the place of node, which is nil if the visitor removed it.
+func Walk(node Node, v Visitor) Node {
+	replacement, _ := walk(node, v)
+	return replacement
+}
+
+// Walk visits every node in the document depth first, applying any replacements to the document.
+func (d *Document) Walk(v Visitor) {
+	walkNodes(&d.Children, v)
+}
+
+// Returns the replacement for node, and whether walking should stop.
+func walk(node Node, v Visitor) (Node, bool) {
+	replacement, status := v.Enter(node)
+	if status == WalkStop {
+		return replacement, true
+	}
+
+	if replacement == nil {
+		return nil, false
+	}
+
+	node = replacement
+	if status != WalkSkipChildren {
+		if stop := walkChildren(node, v); stop {
+			return node, true
+		}
+	}
+
+	replacement, status = v.Exit(node)
+	return replacement, status == WalkStop
+}
+
+func walkChildren(node Node, v Visitor) bool {
+	switch n := node.(type) {
+	case *Heading:
+		return walkNodes(&n.Content, v)
+	case *Paragraph:
+		return walkNodes(&n.Content, v)
+	case *Bold:
+		return walkNodes(&n.Content, v)
+	case *Italic:
+		return walkNodes(&n.Content, v)
+	case *BlockQuote:
+		return walkNodes(&n.Content, v)
+	case *Link:
+		return walkNodes(&n.Content, v)
+	case *Button:
+		return walkNodes(&n.Content, v)
+	case *Span:
+		return walkNodes(&n.Content, v)
+	case *Div:
+		return walkNodes(&n.Children, v)
+	case *Nav:
+		return walkNodes(&n.Children, v)
+	case *ListItem:
+		component, stop := walk(n.Component, v)
+		if component == nil {
+			// list items always need content to render
+			component = &Fragment{}
+		}
+		n.Component = component
+		if stop {
+			return true
+		}
+		return walkNodes(&n.Children, v)
+	case *OrderedList:
+		return walkListItems(&n.ListItems, v)
+	case *UnorderedList:
+		return walkListItems(&n.ListItems, v)
+	case *Table:
+		header, stop := walk(&n.Header, v)
+		if row, ok := header.(*TableRow); ok {
+			n.Header = *row
+		}
+		if stop {
+			return true
+		}
+		return walkTableRows(&n.Rows, v)
+	case *TableRow:
+		return walkTableCells(&n.Cells, v)
+	case *TableCell:
+		return walkNodes(&n.Content, v)
+	}
+
+	return false
+}
+
+// Walks each node, replacing or removing them in place.
+func walkNodes(nodes *[]Node, v Visitor) bool {
+	kept := (*nodes)[:0]
+	stop := false
+
+	for _, node := range *nodes {
+		if stop {
+			kept = append(kept, node)
+			continue
+		}
+
+		var replacement Node
+		replacement, stop = walk(node, v)
+		if replacement != nil {
+			kept = append(kept, replacement)
+		}
+	}
+
+	*nodes = kept
+	return stop
+}
+
+// Walks each list item. Items replaced by a node other than a *ListItem become list items containing that node.
+func walkListItems(items *[]ListItem, v Visitor) bool {
+	kept := make([]ListItem, 0, len(*items))
+	changed := false
+	stop := false
+
+	for i := range *items {
+		item := &(*items)[i]
+		if stop {
+			kept = append(kept, *item)
+			continue
+		}
+
+		var replacement Node
+		replacement, stop = walk(item, v)
+		switch r := replacement.(type) {
+		case nil:
+			changed = true
+		case *ListItem:
+			changed = changed || r != item
+			kept = append(kept, *r)
+		default:
+			changed = true
+			kept = append(kept, ListItem{Component: r})
+		}
+	}
+
+	if changed {
+		*items = kept
+	}
+
+	return stop
+}
+
+// Walks each table row. Rows replaced by nil are removed, and replacements that aren't a *TableRow are ignored.
+func walkTableRows(rows *[]TableRow, v Visitor) bool {
+	kept := make([]TableRow, 0, len(*rows))
+	stop := false
+
+	for i := range *rows {
+		row := &(*rows)[i]
+		if stop {
+			kept = append(kept, *row)
+			continue
+		}
+
+		var replacement Node
+		replacement, stop = walk(row, v)
+		switch r := replacement.(type) {
+		case nil:
+		case *TableRow:
+			kept = append(kept, *r)
+		default:
+			kept = append(kept, *row)
+		}
+	}
+
+	*rows = kept
+	return stop
+}
+
+// Walks each cell of a row. Cells replaced by nil are left empty rather than removed, so that they stay in their
+// column, and replacements that aren't a *TableCell are ignored.
+func walkTableCells(cells *[]TableCell, v Visitor) bool {
+	for i := range *cells {
+		cell := &(*cells)[i]
+		replacement, stop := walk(cell, v)
+		switch r := replacement.(type) {
+		case nil:
+			*cell = TableCell{SourceRange: cell.SourceRange, Header: cell.Header, Align: cell.Align}
+		case *TableCell:
+			*cell = *r
+		}
+
+		if stop {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+package mdx
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func parseDocument(t *testing.T, input string) *Document {
+	t.Helper()
+	document, err := Parse([]byte(input))
+	if err != nil {
+		fail(t, err.Error())
+		t.FailNow()
+	}
+	return document
+}
+
+func TestWalkVisitsContainers(t *testing.T) {
+	input := `# Heading with [link](/heading)
+[
+	> Quote with [link](/quote)
+	@ [Nav](/nav) @
+	$ [span](/span) $
+]
+~[[button](/button)](handleClick)
+
+- item`
+	document := parseDocument(t, input)
+
+	var urls []string
+	var entered, exited int
+	document.Walk(VisitorFunc(func(node Node, entering bool) (Node, WalkStatus) {
+		if entering {
+			entered++
+			if l, ok := node.(*Link); ok {
+				urls = append(urls, l.Url)
+			}
+		} else {
+			exited++
+		}
+		return node, WalkContinue
+	}))
+
+	expected := []string{"/heading", "/quote", "/nav", "/span", "/button"}
+	if !reflect.DeepEqual(urls, expected) {
+		fail(t, fmt.Sprintf("Expected links %v, got=%v", expected, urls))
+	}
+
+	if entered != exited {
+		fail(t, fmt.Sprintf("Expected every entered node to be exited, entered=%d exited=%d", entered, exited))
+	}
+}
+
+func TestWalkModifiesNodes(t *testing.T) {
+	input := `# Title
+## Section
+![cat](cat.png)
+[
+	## Nested
+]`
+	document := parseDocument(t, input)
+
+	document.Walk(VisitorFunc(func(node Node, entering bool) (Node, WalkStatus) {
+		switch n := node.(type) {
+		case *Heading:
+			if entering && n.Level == 2 {
+				n.Properties = append(n.Properties, Property{Name: "class", Value: "section"})
+			}
+		case *Image:
+			if entering {
+				n.ImgUrl = "https://cdn.example.com/" + n.ImgUrl
+			}
+		}
+		return node, WalkContinue
+	}))
+
+	expected := `
+    <div>
+        <h1>Title</h1>
+        <h2 class="section">Section</h2>
+        <img src="https://cdn.example.com/cat.png" alt="cat"/>
+        <div>
+            <h2 class="section">Nested</h2>
+        </div>
+    </div>
+`
+	if actual := document.Html(); actual != expected {
+		fail(t, fmt.Sprintf("Expected %q, got=%q", expected, actual))
+	}
+}
+
+func TestWalkReplacesAndRemovesNodes(t *testing.T) {
+	input := `# Title
+---
+Hello **world**
+
+- one
+- two`
+	document := parseDocument(t, input)
+
+	document.Walk(VisitorFunc(func(node Node, entering bool) (Node, WalkStatus) {
+		if !entering {
+			return node, WalkContinue
+		}
+
+		switch n := node.(type) {
+		case *HorizontalRule:
+			return nil, WalkContinue
+		case *Bold:
+			return &Italic{Content: n.Content}, WalkContinue
+		case *ListItem:
+			if frag, ok := n.Component.(*Paragraph).Content[0].(*Fragment); ok && frag.Value == "two" {
+				return nil, WalkContinue
+			}
+		}
+		return node, WalkContinue
+	}))
+
+	validateLength(t, len(document.Children), 3)
+
+	p := document.Children[1].(*Paragraph)
+	if _, ok := p.Content[1].(*Italic); !ok {
+		fail(t, fmt.Sprintf("Expected Bold to be replaced by Italic, got=%T", p.Content[1]))
+	}
+
+	list := document.Children[2].(*UnorderedList)
+	validateLength(t, len(list.ListItems), 1)
+}
+
+func TestWalkSkipAndStop(t *testing.T) {
+	input := `[
+	# Inside
+]
+# Outside
+# Never`
+	document := parseDocument(t, input)
+
+	var headings []string
+	document.Walk(VisitorFunc(func(node Node, entering bool) (Node, WalkStatus) {
+		if !entering {
+			return node, WalkContinue
+		}
+
+		switch n := node.(type) {
+		case *Div:
+			return node, WalkSkipChildren
+		case *Heading:
+			headings = append(headings, n.InnerHtml())
+			return node, WalkStop
+		}
+		return node, WalkContinue
+	}))
+
+	expected := []string{"Outside"}
+	if !reflect.DeepEqual(headings, expected) {
+		fail(t, fmt.Sprintf("Expected headings %v, got=%v", expected, headings))
+	}
+
+	validateLength(t, len(document.Children), 3)
+}
+
+func TestWalkExitReplacement(t *testing.T) {
+	span := &Span{Content: []Node{&Fragment{Value: "a"}, &Bold{Content: []Node{&Fragment{Value: "b"}}}}}
+
+	// flatten span into a single fragment once its children have been visited
+	replacement := Walk(span, VisitorFunc(func(node Node, entering bool) (Node, WalkStatus) {
+		if s, ok := node.(*Span); ok && !entering {
+			return &Fragment{Value: s.InnerHtml()}, WalkContinue
+		}
+		return node, WalkContinue
+	}))
+
+	expected := &Fragment{Value: "a<strong>b</strong>"}
+	if !reflect.DeepEqual(replacement, expected) {
+		fail(t, fmt.Sprintf("Expected %v, got=%v", expected, replacement))
+	}
+}
+
+func TestWalkVisitsNestedLists(t *testing.T) {
+	document := parseDocument(t, "- outer\n  - inner\n    - innermost")
+
+	var texts []string
+	document.Walk(VisitorFunc(func(node Node, entering bool) (Node, WalkStatus) {
+		if f, ok := node.(*Fragment); ok && entering {
+			texts = append(texts, f.Value)
+		}
+		return node, WalkContinue
+	}))
+
+	expected := []string{"outer", "inner", "innermost"}
+	if !reflect.DeepEqual(texts, expected) {
+		fail(t, fmt.Sprintf("Expected %v, got=%v", expected, texts))
+	}
+}
+
+func TestWalkVisitsTableCells(t *testing.T) {
+	document := parseDocument(t, "| a | b |\n| - | - |\n| c | d |\n| e | f |")
+
+	var texts []string
+	document.Walk(VisitorFunc(func(node Node, entering bool) (Node, WalkStatus) {
+		if f, ok := node.(*Fragment); ok && entering {
+			texts = append(texts, f.Value)
+		}
+		if row, ok := node.(*TableRow); ok && entering && len(row.Cells) > 0 && row.Cells[0].Header {
+			return node, WalkSkipChildren
+		}
+		if cell, ok := node.(*TableCell); ok && entering && cell.InnerHtml() == "d" {
+			return nil, WalkContinue
+		}
+		return node, WalkContinue
+	}))
+
+	expected := []string{"c", "e", "f"}
+	if !reflect.DeepEqual(texts, expected) {
+		fail(t, fmt.Sprintf("Expected %v, got=%v", expected, texts))
+	}
+
+	table := document.Children[0].(*Table)
+	if cells := table.Rows[0].Cells; len(cells) != 2 || len(cells[1].Content) != 0 {
+		fail(t, fmt.Sprintf("Expected removed cell to be left empty, got=%s", table.Rows[0].Raw()))
+	}
+}